@@ -0,0 +1,85 @@
+package cvc
+
+import (
+	"context"
+	"reflect"
+	"sync"
+)
+
+var (
+	bindingsLock sync.RWMutex
+	bindings     = map[reflect.Type]map[string]BoundMethod{}
+)
+
+// BoundMethod is the hook method of the binding; it is made by BindParse,
+// BindError, BindContext and BindValue, so the signature of the method is
+// checked at compile time and the method is called without reflection.
+type BoundMethod struct {
+	fn   interface{}
+	call func(body reflect.Value, args []reflect.Value) []reflect.Value
+}
+
+// BindParse binds `Parse` and `ParseEnv` hooks, like `(*Config).ParseLevel`.
+func BindParse[B, I, O any](f func(B, I) (O, error)) BoundMethod {
+	return BoundMethod{
+		fn: f,
+		call: func(body reflect.Value, args []reflect.Value) []reflect.Value {
+			o, err := f(body.Interface().(B), args[0].Interface().(I))
+			return []reflect.Value{reflect.ValueOf(&o).Elem(), reflect.ValueOf(&err).Elem()}
+		},
+	}
+}
+
+// BindError binds the hooks without argument, like `Validate`, `Merge` and
+// `AfterFlags`.
+func BindError[B any](f func(B) error) BoundMethod {
+	return BoundMethod{
+		fn: f,
+		call: func(body reflect.Value, _ []reflect.Value) []reflect.Value {
+			err := f(body.Interface().(B))
+			return []reflect.Value{reflect.ValueOf(&err).Elem()}
+		},
+	}
+}
+
+// BindContext binds `Validate(context.Context) error`.
+func BindContext[B any](f func(B, context.Context) error) BoundMethod {
+	return BoundMethod{
+		fn: f,
+		call: func(body reflect.Value, args []reflect.Value) []reflect.Value {
+			err := f(body.Interface().(B), args[0].Interface().(context.Context))
+			return []reflect.Value{reflect.ValueOf(&err).Elem()}
+		},
+	}
+}
+
+// BindValue binds `FlagValue` and `StringVar`.
+func BindValue[B, O any](f func(B) O) BoundMethod {
+	return BoundMethod{
+		fn: f,
+		call: func(body reflect.Value, _ []reflect.Value) []reflect.Value {
+			o := f(body.Interface().(B))
+			return []reflect.Value{reflect.ValueOf(&o).Elem()}
+		},
+	}
+}
+
+// RegisterBinding registers the hook methods of T, like `ParseLevel` and
+// `Validate`; it is called by the code generated by `cvcgen`. The methods of
+// the registered type are looked up from the binding instead of reflection;
+// the methods, which are not in the stale binding, are still found by
+// reflection.
+func RegisterBinding[T any](methods map[string]BoundMethod) {
+	bindingsLock.Lock()
+	defer bindingsLock.Unlock()
+
+	bindings[reflect.TypeOf((*T)(nil)).Elem()] = methods
+}
+
+func lookupBinding(t reflect.Type) (map[string]BoundMethod, bool) {
+	bindingsLock.RLock()
+	defer bindingsLock.RUnlock()
+
+	methods, found := bindings[t]
+	return methods, found
+}
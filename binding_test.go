@@ -0,0 +1,76 @@
+package cvc
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type testBindingConfig struct {
+	BaseGroup
+
+	A int
+}
+
+func (c *testBindingConfig) ParseA(i string) (int, error) {
+	n, err := strconv.ParseInt(i, 10, 64)
+	return int(n), err
+}
+
+func (c *testBindingConfig) parseDoubleA(i string) (int, error) {
+	n, err := c.ParseA(i)
+	return n * 2, err
+}
+
+func (c *testBindingConfig) Validate() error {
+	if c.A > 5 {
+		return errors.New("too big")
+	}
+
+	return nil
+}
+
+type testBinding struct {
+	suite.Suite
+}
+
+func (t *testBinding) TestRegister() {
+	RegisterBinding[*testBindingConfig](map[string]BoundMethod{
+		"ParseA": BindParse((*testBindingConfig).parseDoubleA),
+		// the wrong arity is not found like reflection
+		"ValidateA": BindParse((*testBindingConfig).ParseA),
+	})
+	defer func() {
+		bindingsLock.Lock()
+		defer bindingsLock.Unlock()
+
+		delete(bindings, reflect.TypeOf((*testBindingConfig)(nil)))
+	}()
+
+	config := &testBindingConfig{}
+
+	manager := newTestManager(t.T(), config, nil, []string{"--a", "3"})
+
+	// Validate is not in the binding, but it is found by reflection
+	_, err := manager.Merge()
+	t.Error(err)
+	t.Contains(err.Error(), "too big")
+	t.Equal(6, config.A)
+
+	_, found := GetMethodByName(config, "Validate", 0, 1)
+	t.True(found)
+
+	_, found = GetMethodByName(config, "ValidateA", 0, 1)
+	t.False(found)
+
+	m, found := GetMethodByName(config, "ParseA", 1, 2)
+	t.True(found)
+	t.Equal(reflect.TypeOf(""), m.In(0))
+}
+
+func TestBinding(t *testing.T) {
+	suite.Run(t, new(testBinding))
+}
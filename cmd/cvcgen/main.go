@@ -0,0 +1,401 @@
+// cvcgen generates the static binding of the hook methods, like `ParseXxx`,
+// `ValidateXxx` and `MergeXxx`, of the config struct for cvc. The hook methods
+// of the generated types are called through the typed functions of the
+// binding instead of reflection, so the wrong signatures and the renamed
+// fields or methods break the build; the hook methods, which do not match any
+// field, fail the generation. The flags and env names are not generated,
+// because they depend on the naming options of Manager at runtime.
+//
+//	//go:generate go run github.com/spikeekips/cvc/cmd/cvcgen -type Config
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/spikeekips/cvc"
+)
+
+type binding struct {
+	typ     string
+	methods []string
+	fields  []string
+}
+
+type generator struct {
+	pkg      *types.Package
+	seen     map[string]bool
+	bindings []binding
+}
+
+func main() {
+	typeName := flag.String("type", "", "name of the config struct")
+	output := flag.String("output", "", "output file name; default is <type>_cvc.go")
+	flag.Parse()
+
+	if len(*typeName) < 1 {
+		fmt.Fprintln(os.Stderr, "cvcgen: -type is required")
+		os.Exit(2)
+	}
+
+	b, err := generate(".", *typeName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "cvcgen:", err)
+		os.Exit(1)
+	}
+
+	name := *output
+	if len(name) < 1 {
+		name = strings.ToLower(*typeName) + "_cvc.go"
+	}
+
+	if err := ioutil.WriteFile(filepath.Clean(name), b, 0644); err != nil {
+		fmt.Fprintln(os.Stderr, "cvcgen:", err)
+		os.Exit(1)
+	}
+}
+
+func generate(dir, typeName string) ([]byte, error) {
+	pkg, err := loadPackage(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	obj, found := pkg.Scope().Lookup(typeName).(*types.TypeName)
+	if !found {
+		return nil, fmt.Errorf("type not found: '%s'", typeName)
+	}
+	if _, isStruct := obj.Type().Underlying().(*types.Struct); !isStruct {
+		return nil, fmt.Errorf("'%s' is not struct", typeName)
+	}
+
+	g := &generator{pkg: pkg, seen: map[string]bool{}}
+	if err := g.walk(types.NewPointer(obj.Type()), true); err != nil {
+		return nil, err
+	}
+
+	return g.source()
+}
+
+// loadPackage type-checks the package in dir; the imported packages are
+// loaded from the export data of `go list -export`. The code generated by
+// cvcgen is skipped, so the stale binding, which does not compile, can be
+// generated again.
+func loadPackage(dir string) (*types.Package, error) {
+	bp, err := build.ImportDir(dir, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	fset := token.NewFileSet()
+	var files []*ast.File
+	imports := map[string]bool{}
+	for _, name := range bp.GoFiles {
+		f, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		if len(f.Comments) > 0 && strings.HasPrefix(f.Comments[0].Text(), "Code generated by cvcgen") {
+			continue
+		}
+
+		files = append(files, f)
+		for _, i := range f.Imports {
+			imports[strings.Trim(i.Path.Value, `"`)] = true
+		}
+	}
+
+	out, err := goList(dir, "-f", "{{.ImportPath}}", ".")
+	if err != nil {
+		return nil, err
+	}
+	path := strings.TrimSpace(out)
+
+	exports := map[string]string{}
+	if len(imports) > 0 {
+		args := []string{"-deps", "-export", "-f", "{{.ImportPath}} {{.Export}}"}
+		for i := range imports {
+			args = append(args, i)
+		}
+
+		out, err := goList(dir, args...)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, l := range strings.Split(strings.TrimSpace(out), "\n") {
+			if f := strings.Fields(l); len(f) == 2 {
+				exports[f[0]] = f[1]
+			}
+		}
+	}
+
+	conf := types.Config{
+		Importer: importer.ForCompiler(fset, "gc", func(path string) (io.ReadCloser, error) {
+			f, found := exports[path]
+			if !found {
+				return nil, fmt.Errorf("export data not found: '%s'", path)
+			}
+			return os.Open(f)
+		}),
+	}
+
+	return conf.Check(path, fset, files, nil)
+}
+
+func goList(dir string, args ...string) (string, error) {
+	cmd := exec.Command("go", append([]string{"list"}, args...)...)
+	cmd.Dir = dir
+	cmd.Stderr = os.Stderr
+
+	out, err := cmd.Output()
+
+	return string(out), err
+}
+
+func (g *generator) walk(t types.Type, isGroup bool) error {
+	named, isPointer := deref(t)
+	if named == nil || named.Obj().Pkg() != g.pkg {
+		return nil
+	}
+
+	typ := types.TypeString(t, types.RelativeTo(g.pkg))
+	if g.seen[typ] {
+		return nil
+	}
+	g.seen[typ] = true
+
+	var fields []string
+	if st, ok := named.Underlying().(*types.Struct); ok && isGroup {
+		var err error
+		if fields, err = g.fields(st); err != nil {
+			return err
+		}
+	}
+
+	b := binding{typ: typ}
+
+	ms := types.NewMethodSet(t)
+	for i := 0; i < ms.Len(); i++ {
+		name := ms.At(i).Obj().Name()
+
+		hook, field, matched, isHook := cvc.SplitHookMethodName(name, fields)
+		switch {
+		case matched:
+			bind, expected := bindFunc(hook, ms.At(i).Type().(*types.Signature))
+			if len(bind) < 1 {
+				return fmt.Errorf("%s.%s must be %s", typ, name, expected)
+			}
+
+			expr := named.Obj().Name() + "." + name
+			if isPointer {
+				expr = "(*" + named.Obj().Name() + ")." + name
+			}
+			b.methods = append(b.methods, fmt.Sprintf("%q: cvc.%s(%s),", name, bind, expr))
+
+			if len(field) > 0 {
+				b.fields = append(b.fields, field)
+			}
+		case isHook && isGroup:
+			return fmt.Errorf("%s.%s does not match any field", typ, name)
+		}
+	}
+
+	g.bindings = append(g.bindings, b)
+
+	return nil
+}
+
+// fields returns the names of the items of the group; the fields of the
+// embedded struct are flattened.
+func (g *generator) fields(st *types.Struct) ([]string, error) {
+	var fields []string
+	for i := 0; i < st.NumFields(); i++ {
+		f := st.Field(i)
+		if isBaseGroup(f.Type()) {
+			continue
+		}
+
+		isGroupTag := reflect.StructTag(st.Tag(i)).Get("group") == "true"
+		if f.Embedded() && !isGroupTag && isStructType(f.Type()) {
+			n, err := g.fields(structOf(f.Type()))
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, n...)
+			continue
+		}
+
+		if !f.Exported() {
+			continue
+		}
+
+		fields = append(fields, f.Name())
+
+		// the element of the list or map of groups
+		if et := elemOf(f.Type()); et != nil && (implementsGroup(et) || (isGroupTag && isStructType(et))) {
+			if err := g.walk(et, true); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if err := g.walk(f.Type(), implementsGroup(f.Type()) || (isGroupTag && isStructType(f.Type()))); err != nil {
+			return nil, err
+		}
+	}
+
+	return fields, nil
+}
+
+// bindFunc returns the name of the bind function of cvc for the signature of
+// hook method; expected is the valid signature of the hook.
+func bindFunc(hook string, sig *types.Signature) (bind, expected string) {
+	params, results := sig.Params(), sig.Results()
+	returnsError := results.Len() > 0 && types.Identical(results.At(results.Len()-1).Type(), errorType)
+
+	switch hook {
+	case "Parse", "ParseEnv":
+		if params.Len() == 1 && results.Len() == 2 && returnsError {
+			return "BindParse", ""
+		}
+		return "", "func(input) (T, error)"
+	case "Validate":
+		switch {
+		case params.Len() == 0 && results.Len() == 1 && returnsError:
+			return "BindError", ""
+		case params.Len() == 1 && results.Len() == 1 && returnsError && isContext(params.At(0).Type()):
+			return "BindContext", ""
+		}
+		return "", "func() error or func(context.Context) error"
+	case "FlagValue", "StringVar":
+		if params.Len() == 0 && results.Len() == 1 {
+			return "BindValue", ""
+		}
+		return "", "func() T"
+	default:
+		if params.Len() == 0 && results.Len() == 1 && returnsError {
+			return "BindError", ""
+		}
+		return "", "func() error"
+	}
+}
+
+var errorType = types.Universe.Lookup("error").Type()
+
+func isContext(t types.Type) bool {
+	n, _ := t.(*types.Named)
+
+	return n != nil && n.Obj().Pkg() != nil && n.Obj().Pkg().Path() == "context" && n.Obj().Name() == "Context"
+}
+
+func (g *generator) source() ([]byte, error) {
+	var w bytes.Buffer
+	fmt.Fprintln(&w, "// Code generated by cvcgen; DO NOT EDIT.")
+	fmt.Fprintln(&w)
+	fmt.Fprintf(&w, "package %s\n\n", g.pkg.Name())
+	fmt.Fprintln(&w, `import "github.com/spikeekips/cvc"`)
+	fmt.Fprintln(&w)
+	fmt.Fprintln(&w, "func init() {")
+	for _, b := range g.bindings {
+		sort.Strings(b.methods)
+
+		fmt.Fprintf(&w, "cvc.RegisterBinding[%s](map[string]cvc.BoundMethod{\n", b.typ)
+		for _, m := range b.methods {
+			fmt.Fprintln(&w, m)
+		}
+		fmt.Fprintln(&w, "})")
+	}
+	fmt.Fprintln(&w, "}")
+
+	// the fields of the hook methods are referred, so the build fails when
+	// the field is renamed or removed.
+	for _, b := range g.bindings {
+		if len(b.fields) < 1 {
+			continue
+		}
+		sort.Strings(b.fields)
+
+		fmt.Fprintf(&w, "\nfunc _(c %s) {\n", b.typ)
+		for _, f := range b.fields {
+			fmt.Fprintf(&w, "_ = c.%s\n", f)
+		}
+		fmt.Fprintln(&w, "}")
+	}
+
+	return format.Source(w.Bytes())
+}
+
+func deref(t types.Type) (*types.Named, bool) {
+	if p, ok := t.(*types.Pointer); ok {
+		n, _ := p.Elem().(*types.Named)
+		return n, true
+	}
+
+	n, _ := t.(*types.Named)
+	return n, false
+}
+
+func structOf(t types.Type) *types.Struct {
+	if p, ok := t.(*types.Pointer); ok {
+		t = p.Elem()
+	}
+
+	st, _ := t.Underlying().(*types.Struct)
+	return st
+}
+
+// elemOf returns the pointer type of the element of slice or the pointer
+// value type of map, which has string key.
+func elemOf(t types.Type) types.Type {
+	switch u := t.Underlying().(type) {
+	case *types.Slice:
+		if _, isPointer := u.Elem().(*types.Pointer); isPointer {
+			return u.Elem()
+		}
+		return types.NewPointer(u.Elem())
+	case *types.Map:
+		b, ok := u.Key().Underlying().(*types.Basic)
+		if _, isPointer := u.Elem().(*types.Pointer); ok && isPointer && b.Kind() == types.String {
+			return u.Elem()
+		}
+	}
+
+	return nil
+}
+
+func isStructType(t types.Type) bool {
+	return structOf(t) != nil
+}
+
+func isBaseGroup(t types.Type) bool {
+	n, _ := deref(t)
+	if n == nil || n.Obj().Pkg() == nil {
+		return false
+	}
+
+	return n.Obj().Pkg().Path() == "github.com/spikeekips/cvc" && n.Obj().Name() == "BaseGroup"
+}
+
+func implementsGroup(t types.Type) bool {
+	obj, _, _ := types.LookupFieldOrMethod(t, false, nil, "ThisIsGroup")
+	_, isFunc := obj.(*types.Func)
+
+	return isFunc
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type testGenerate struct {
+	suite.Suite
+}
+
+func (t *testGenerate) TestGenerate() {
+	b, err := generate("testdata/config", "Config")
+	t.NoError(err)
+
+	t.Equal(`// Code generated by cvcgen; DO NOT EDIT.
+
+package config
+
+import "github.com/spikeekips/cvc"
+
+func init() {
+	cvc.RegisterBinding[LogLevel](map[string]cvc.BoundMethod{
+		"Parse": cvc.BindParse(LogLevel.Parse),
+	})
+	cvc.RegisterBinding[*LogConfig](map[string]cvc.BoundMethod{
+		"Merge":        cvc.BindError((*LogConfig).Merge),
+		"Validate":     cvc.BindError((*LogConfig).Validate),
+		"ValidateFile": cvc.BindContext((*LogConfig).ValidateFile),
+	})
+	cvc.RegisterBinding[*UpstreamConfig](map[string]cvc.BoundMethod{
+		"Merge":        cvc.BindError((*UpstreamConfig).Merge),
+		"Validate":     cvc.BindError((*UpstreamConfig).Validate),
+		"ValidateHost": cvc.BindError((*UpstreamConfig).ValidateHost),
+	})
+	cvc.RegisterBinding[*DatabaseConfig](map[string]cvc.BoundMethod{
+		"Merge":    cvc.BindError((*DatabaseConfig).Merge),
+		"ParseDSN": cvc.BindParse((*DatabaseConfig).ParseDSN),
+		"Validate": cvc.BindError((*DatabaseConfig).Validate),
+	})
+	cvc.RegisterBinding[*Config](map[string]cvc.BoundMethod{
+		"AfterFlagsLog": cvc.BindError((*Config).AfterFlagsLog),
+		"Merge":         cvc.BindError((*Config).Merge),
+		"ParseEnvPort":  cvc.BindParse((*Config).ParseEnvPort),
+		"ParseHome":     cvc.BindParse((*Config).ParseHome),
+		"Validate":      cvc.BindError((*Config).Validate),
+	})
+}
+
+func _(c *LogConfig) {
+	_ = c.File
+}
+
+func _(c *UpstreamConfig) {
+	_ = c.Host
+}
+
+func _(c *DatabaseConfig) {
+	_ = c.DSN
+}
+
+func _(c *Config) {
+	_ = c.Home
+	_ = c.Log
+	_ = c.Port
+}
+`, string(b))
+}
+
+func (t *testGenerate) TestMisspelled() {
+	_, err := generate("testdata/misspelled", "Config")
+	t.EqualError(err, "*Config.ParseLevle does not match any field")
+}
+
+func (t *testGenerate) TestCompile() {
+	// the stale binding, which does not compile, is skipped
+	f := filepath.Join("testdata", "config", "config_cvc.go")
+	t.NoError(ioutil.WriteFile(f, []byte("// Code generated by cvcgen; DO NOT EDIT.\n\npackage config\n\nvar _ = stale\n"), 0644))
+	defer os.Remove(f)
+
+	b, err := generate("testdata/config", "Config")
+	t.NoError(err)
+	t.NoError(ioutil.WriteFile(f, b, 0644))
+
+	cmd := exec.Command("go", "vet", ".")
+	cmd.Dir = filepath.Dir(f)
+	out, err := cmd.CombinedOutput()
+	t.NoError(err, string(out))
+}
+
+func (t *testGenerate) TestSignature() {
+	_, err := generate("testdata/signature", "Config")
+	t.EqualError(err, "*Config.ParseLevel must be func(input) (T, error)")
+}
+
+func (t *testGenerate) TestUnknownType() {
+	_, err := generate("testdata/config", "Unknown")
+	t.EqualError(err, "type not found: 'Unknown'")
+}
+
+func TestGenerate(t *testing.T) {
+	suite.Run(t, new(testGenerate))
+}
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spikeekips/cvc"
+)
+
+type LogLevel string
+
+func (l LogLevel) Parse(i string) (LogLevel, error) {
+	return LogLevel(i), nil
+}
+
+type LogConfig struct {
+	cvc.BaseGroup
+
+	File  string
+	Level LogLevel
+}
+
+func (l *LogConfig) ValidateFile(ctx context.Context) error {
+	return nil
+}
+
+func (l *LogConfig) String() string {
+	return l.File
+}
+
+type UpstreamConfig struct {
+	cvc.BaseGroup
+
+	Host string
+}
+
+func (u *UpstreamConfig) ValidateHost() error {
+	return nil
+}
+
+type DatabaseConfig struct {
+	cvc.BaseGroup
+
+	DSN string
+}
+
+func (d *DatabaseConfig) ParseDSN(i string) (string, error) {
+	return i, nil
+}
+
+type Common struct {
+	Home string
+}
+
+type Config struct {
+	cvc.BaseGroup
+	Common
+
+	Port      int
+	Log       *LogConfig
+	Upstreams []UpstreamConfig
+	Databases map[string]*DatabaseConfig
+}
+
+func (c *Config) ParseHome(i string) (string, error) {
+	return i, nil
+}
+
+func (c *Config) ParseEnvPort(i string) (int, error) {
+	var n int
+	_, err := fmt.Sscan(i, &n)
+	return n, err
+}
+
+func (c *Config) AfterFlagsLog() error {
+	return nil
+}
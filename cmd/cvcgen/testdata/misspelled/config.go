@@ -0,0 +1,15 @@
+package misspelled
+
+import (
+	"github.com/spikeekips/cvc"
+)
+
+type Config struct {
+	cvc.BaseGroup
+
+	Level string
+}
+
+func (c *Config) ParseLevle(i string) (string, error) {
+	return i, nil
+}
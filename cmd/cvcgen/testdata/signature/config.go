@@ -0,0 +1,15 @@
+package signature
+
+import (
+	"github.com/spikeekips/cvc"
+)
+
+type Config struct {
+	cvc.BaseGroup
+
+	Level string
+}
+
+func (c *Config) ParseLevel(i string) string {
+	return i
+}
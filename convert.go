@@ -0,0 +1,74 @@
+package cvc
+
+import (
+	"reflect"
+
+	"github.com/spf13/cast"
+)
+
+// convertValue converts the input of config, env and flag to the type of
+// field or the input of `ParseXXX` hook, like "10" to int and "1s" to
+// time.Duration. The input of the other types is returned as it is.
+func convertValue(t reflect.Type, i interface{}) (interface{}, error) {
+	if i == nil && t.Kind() == reflect.Ptr {
+		return reflect.Zero(t).Interface(), nil
+	}
+	if i == nil || reflect.TypeOf(i).AssignableTo(t) {
+		return i, nil
+	}
+
+	// the pointer of scalar value is parsed by the type of element
+	if t.Kind() == reflect.Ptr && t.Elem().Kind() != reflect.Struct {
+		e, err := convertValue(t.Elem(), i)
+		if err != nil {
+			return nil, err
+		}
+
+		p := reflect.New(t.Elem())
+		p.Elem().Set(reflect.ValueOf(e))
+		return p.Interface(), nil
+	}
+
+	var r interface{}
+	var err error
+	switch {
+	case t == durationType:
+		r, err = cast.ToDurationE(i)
+	case t.Kind() == reflect.Bool:
+		r, err = cast.ToBoolE(i)
+	case t.Kind() == reflect.Int:
+		r, err = cast.ToIntE(i)
+	case t.Kind() == reflect.Int8:
+		r, err = cast.ToInt8E(i)
+	case t.Kind() == reflect.Int16:
+		r, err = cast.ToInt16E(i)
+	case t.Kind() == reflect.Int32:
+		r, err = cast.ToInt32E(i)
+	case t.Kind() == reflect.Int64:
+		r, err = cast.ToInt64E(i)
+	case t.Kind() == reflect.Uint:
+		r, err = cast.ToUintE(i)
+	case t.Kind() == reflect.Uint8:
+		r, err = cast.ToUint8E(i)
+	case t.Kind() == reflect.Uint16:
+		r, err = cast.ToUint16E(i)
+	case t.Kind() == reflect.Uint32:
+		r, err = cast.ToUint32E(i)
+	case t.Kind() == reflect.Uint64:
+		r, err = cast.ToUint64E(i)
+	case t.Kind() == reflect.Float32:
+		r, err = cast.ToFloat32E(i)
+	case t.Kind() == reflect.Float64:
+		r, err = cast.ToFloat64E(i)
+	case t.Kind() == reflect.String:
+		r, err = cast.ToStringE(i)
+	default:
+		return i, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return reflect.ValueOf(r).Convert(t).Interface(), nil
+}
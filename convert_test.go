@@ -0,0 +1,76 @@
+package cvc
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type testConvert struct {
+	suite.Suite
+}
+
+func (t *testConvert) TestScalar() {
+	cases := []struct {
+		t        reflect.Type
+		input    interface{}
+		expected interface{}
+	}{
+		{t: reflect.TypeOf(int(0)), input: "10", expected: 10},
+		{t: reflect.TypeOf(uint16(0)), input: 10.0, expected: uint16(10)},
+		{t: reflect.TypeOf(false), input: "true", expected: true},
+		{t: reflect.TypeOf(""), input: 10, expected: "10"},
+		{t: durationType, input: "1s", expected: time.Second},
+	}
+
+	for _, c := range cases {
+		v, err := convertValue(c.t, c.input)
+		t.NoError(err, c.t)
+		t.Equal(c.expected, v, c.t)
+	}
+}
+
+func (t *testConvert) TestPointer() {
+	v, err := convertValue(reflect.TypeOf((*int)(nil)), "10")
+	t.NoError(err)
+	t.Equal(10, *v.(*int))
+
+	v, err = convertValue(reflect.TypeOf((*int)(nil)), nil)
+	t.NoError(err)
+	t.Nil(v.(*int))
+}
+
+func (t *testConvert) TestNotConverted() {
+	i := []string{"a"}
+
+	v, err := convertValue(reflect.TypeOf(map[string]int{}), i)
+	t.NoError(err)
+	t.Equal(i, v)
+
+	_, err = convertValue(reflect.TypeOf(int(0)), "ten")
+	t.Error(err)
+}
+
+type testConvertConfig struct {
+	BaseGroup
+	Timeout time.Duration
+}
+
+func (t *testConvert) TestItemParse() {
+	manager := newTestManager(t.T(), &testConvertConfig{}, nil, nil)
+	item := manager.Map()["timeout"]
+
+	v, err := item.Parse("1s")
+	t.NoError(err)
+	t.Equal("1s", v)
+
+	v, err = item.parse("1s")
+	t.NoError(err)
+	t.Equal(time.Second, v)
+}
+
+func TestConvert(t *testing.T) {
+	suite.Run(t, new(testConvert))
+}
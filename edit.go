@@ -0,0 +1,645 @@
+package cvc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	toml "github.com/pelletier/go-toml"
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+var (
+	regexpTOMLBareKey *regexp.Regexp = regexp.MustCompile("^[A-Za-z0-9_-]+$")
+)
+
+// UpdateFile changes the value of the key in the yaml or toml config file.
+// Only the value text is replaced, so the comments, the order of keys and the
+// formatting of the file are kept. The value is parsed and validated by the
+// item, and the parsed value is written like WriteConfigFile.
+func (m *Manager) UpdateFile(path, key string, value interface{}) error {
+	m.Lock()
+	defer m.Unlock()
+
+	item, found := m.get(key)
+	if !found {
+		return ErrorKeyNotFound.Clone().Set("key", key)
+	}
+	if item.IsGroup {
+		return fmt.Errorf("group can not be updated: '%s'", key)
+	}
+
+	value, err := m.editValue(item, value)
+	if err != nil {
+		return err
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	keys := append([]string{m.group}, strings.Split(key, ".")...)
+
+	var updated []byte
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		updated, err = updateYAML(b, keys, value)
+	case ".toml":
+		updated, err = updateTOML(b, keys, value)
+	default:
+		return fmt.Errorf("unsupported file type for update: '%s'", path)
+	}
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(path, updated)
+}
+
+// editValue parses the value by the item and validates it; the item is
+// restored after validation, and the value of config of the parsed value is
+// returned.
+func (m *Manager) editValue(item *Item, value interface{}) (interface{}, error) {
+	v, err := item.parse(value)
+	if err != nil {
+		return nil, err
+	}
+
+	old := reflect.New(item.Value.Type()).Elem()
+	old.Set(item.Value)
+	defer item.set(old)
+
+	if err := m.setRaw(item.FullName(), v); err != nil {
+		return nil, err
+	}
+	if err := item.validate(context.Background()); err != nil {
+		return nil, &ValidationError{Key: item.FullName(), Source: SourceConfig, Input: value, Err: err}
+	}
+
+	return editScalar(item.configValue()), nil
+}
+
+func editScalar(value interface{}) interface{} {
+	if d, ok := value.(time.Duration); ok {
+		return d.String()
+	}
+
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Bool:
+		return v.Bool()
+	case reflect.String:
+		return v.String()
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint()
+	default:
+		return fmt.Sprintf("%v", value)
+	}
+}
+
+func splitLines(b []byte) []string {
+	if len(b) < 1 {
+		return nil
+	}
+
+	return strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+}
+
+func joinLines(lines []string) []byte {
+	return []byte(strings.Join(lines, "\n") + "\n")
+}
+
+func insertLines(lines []string, at int, inserted ...string) []string {
+	n := make([]string, 0, len(lines)+len(inserted))
+	n = append(n, lines[:at]...)
+	n = append(n, inserted...)
+	return append(n, lines[at:]...)
+}
+
+func indentOf(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " \t"))
+}
+
+// byteOffset converts the 1-based column of the yaml node into the byte offset
+// of the line.
+func byteOffset(line string, column int) int {
+	r := []rune(line)
+	if column-1 > len(r) {
+		return len(line)
+	}
+
+	return len(string(r[:column-1]))
+}
+
+// scalarEnd returns the byte offset where the scalar, which starts at start
+// of the line, ends; quoted strings must be closed in the same line.
+func scalarEnd(line string, start int, escape bool) (int, error) {
+	if start >= len(line) {
+		return start, nil
+	}
+
+	switch q := line[start]; q {
+	case '"', '\'':
+		for i := start + 1; i < len(line); i++ {
+			switch {
+			case escape && q == '"' && line[i] == '\\':
+				i++
+			case line[i] == q && !escape && i+1 < len(line) && line[i+1] == q:
+				i++
+			case line[i] == q:
+				return i + 1, nil
+			}
+		}
+
+		return 0, fmt.Errorf("multi-line value is not supported")
+	}
+
+	end := len(line)
+	for i := start; i < len(line); i++ {
+		if line[i] == '#' && i > start && (line[i-1] == ' ' || line[i-1] == '\t') {
+			end = i
+			break
+		}
+	}
+
+	return start + len(strings.TrimRight(line[start:end], " \t\r")), nil
+}
+
+func yamlScalar(value interface{}, style yaml3.Style) (string, error) {
+	s, isString := value.(string)
+	switch {
+	case !isString:
+	case style&yaml3.DoubleQuotedStyle != 0 || strings.Contains(s, "\n"):
+		return jsonString(s)
+	case style&yaml3.SingleQuotedStyle != 0:
+		return "'" + strings.Replace(s, "'", "''", -1) + "'", nil
+	}
+
+	b, err := yaml3.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+
+	t := strings.TrimSuffix(string(b), "\n")
+	if strings.Contains(t, "\n") {
+		return jsonString(fmt.Sprintf("%v", value))
+	}
+
+	return t, nil
+}
+
+func jsonString(s string) (string, error) {
+	var b bytes.Buffer
+	e := json.NewEncoder(&b)
+	e.SetEscapeHTML(false)
+	if err := e.Encode(s); err != nil {
+		return "", err
+	}
+
+	return strings.TrimSuffix(b.String(), "\n"), nil
+}
+
+func yamlKeyBlock(keys []string, text string, indent, step int) ([]string, error) {
+	var lines []string
+	for i, k := range keys {
+		key, err := yamlScalar(k, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		line := strings.Repeat(" ", indent+i*step) + key + ":"
+		if i == len(keys)-1 {
+			line += " " + text
+		}
+		lines = append(lines, line)
+	}
+
+	return lines, nil
+}
+
+func yamlMappingGet(n *yaml3.Node, key string) (*yaml3.Node, *yaml3.Node) {
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i], n.Content[i+1]
+		}
+	}
+
+	return nil, nil
+}
+
+func yamlMaxLine(n *yaml3.Node) int {
+	line := n.Line
+	for _, c := range n.Content {
+		if l := yamlMaxLine(c); l > line {
+			line = l
+		}
+	}
+
+	return line
+}
+
+func yamlIndentStep(n *yaml3.Node) int {
+	if n.Kind != yaml3.MappingNode {
+		return 0
+	}
+
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		k, v := n.Content[i], n.Content[i+1]
+		if v.Kind == yaml3.MappingNode && v.Style&yaml3.FlowStyle == 0 && len(v.Content) > 0 {
+			if step := v.Content[0].Column - k.Column; step > 0 {
+				return step
+			}
+		}
+		if step := yamlIndentStep(v); step > 0 {
+			return step
+		}
+	}
+
+	return 0
+}
+
+func updateYAML(b []byte, keys []string, value interface{}) ([]byte, error) {
+	lines := splitLines(b)
+
+	var doc yaml3.Node
+	if err := yaml3.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+
+	text, err := yamlScalar(value, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(doc.Content) < 1 {
+		block, err := yamlKeyBlock(keys, text, 0, 2)
+		if err != nil {
+			return nil, err
+		}
+		return validateYAML(joinLines(append(lines, block...)))
+	}
+
+	root := doc.Content[0]
+	if root.Kind != yaml3.MappingNode || root.Style&yaml3.FlowStyle != 0 || len(root.Content) < 1 {
+		return nil, fmt.Errorf("yaml document is not a block mapping")
+	}
+
+	step := yamlIndentStep(root)
+	if step < 1 {
+		step = 2
+	}
+
+	parent := root
+	for i, k := range keys {
+		keyNode, valueNode := yamlMappingGet(parent, k)
+		if keyNode == nil {
+			indent := parent.Content[0].Column - 1
+			at := yamlMaxLine(parent)
+			for j := at; j < len(lines); j++ {
+				if len(strings.TrimSpace(lines[j])) > 0 && indentOf(lines[j]) <= indent {
+					break
+				}
+				if len(strings.TrimSpace(lines[j])) > 0 {
+					at = j + 1
+				}
+			}
+
+			block, err := yamlKeyBlock(keys[i:], text, indent, step)
+			if err != nil {
+				return nil, err
+			}
+			return validateYAML(joinLines(insertLines(lines, at, block...)))
+		}
+
+		line := lines[keyNode.Line-1]
+		if i == len(keys)-1 {
+			if valueNode.Kind != yaml3.ScalarNode || valueNode.Style&(yaml3.LiteralStyle|yaml3.FoldedStyle) != 0 {
+				return nil, fmt.Errorf("'%s' is not a single line scalar", strings.Join(keys, "."))
+			}
+
+			if valueNode.Tag == "!!null" && len(valueNode.Value) < 1 {
+				colon, err := yamlColon(line, byteOffset(line, keyNode.Column))
+				if err != nil {
+					return nil, err
+				}
+				lines[keyNode.Line-1] = line[:colon] + " " + text + line[colon:]
+				return validateYAML(joinLines(lines))
+			}
+
+			text, err = yamlScalar(value, valueNode.Style)
+			if err != nil {
+				return nil, err
+			}
+
+			line = lines[valueNode.Line-1]
+			start := byteOffset(line, valueNode.Column)
+			end, err := scalarEnd(line, start, valueNode.Style&yaml3.DoubleQuotedStyle != 0)
+			if err != nil {
+				return nil, err
+			}
+
+			lines[valueNode.Line-1] = line[:start] + text + line[end:]
+			return validateYAML(joinLines(lines))
+		}
+
+		switch {
+		case valueNode.Kind == yaml3.MappingNode && valueNode.Style&yaml3.FlowStyle == 0 && len(valueNode.Content) > 0:
+			parent = valueNode
+		case valueNode.Kind == yaml3.ScalarNode && valueNode.Tag == "!!null" && len(valueNode.Value) < 1:
+			block, err := yamlKeyBlock(keys[i+1:], text, keyNode.Column-1+step, step)
+			if err != nil {
+				return nil, err
+			}
+			return validateYAML(joinLines(insertLines(lines, keyNode.Line, block...)))
+		default:
+			return nil, fmt.Errorf("'%s' is not a block mapping", strings.Join(keys[:i+1], "."))
+		}
+	}
+
+	return nil, fmt.Errorf("key not found: '%s'", strings.Join(keys, "."))
+}
+
+// yamlColon returns the byte offset just after the colon of the mapping key,
+// which starts at start.
+func yamlColon(line string, start int) (int, error) {
+	i := start
+	if i < len(line) && (line[i] == '"' || line[i] == '\'') {
+		end, err := scalarEnd(line, i, line[i] == '"')
+		if err != nil {
+			return 0, err
+		}
+		i = end
+	}
+
+	for ; i < len(line); i++ {
+		if line[i] == ':' && (i+1 == len(line) || line[i+1] == ' ' || line[i+1] == '\t') {
+			return i + 1, nil
+		}
+	}
+
+	return 0, fmt.Errorf("invalid yaml key: '%s'", line)
+}
+
+func validateYAML(b []byte) ([]byte, error) {
+	var n yaml3.Node
+	if err := yaml3.Unmarshal(b, &n); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+func tomlKeyParts(line string, start int) ([]string, int, error) {
+	var parts []string
+
+	i := start
+	for {
+		for i < len(line) && (line[i] == ' ' || line[i] == '\t') {
+			i++
+		}
+		if i >= len(line) {
+			return nil, 0, fmt.Errorf("invalid toml key: '%s'", line)
+		}
+
+		switch line[i] {
+		case '"', '\'':
+			end, err := scalarEnd(line, i, line[i] == '"')
+			if err != nil {
+				return nil, 0, err
+			}
+			p := line[i+1 : end-1]
+			if line[i] == '"' {
+				if p, err = strconv.Unquote(line[i:end]); err != nil {
+					return nil, 0, err
+				}
+			}
+			parts = append(parts, p)
+			i = end
+		default:
+			j := i
+			for j < len(line) && (line[j] == '_' || line[j] == '-' ||
+				('a' <= line[j] && line[j] <= 'z') || ('A' <= line[j] && line[j] <= 'Z') || ('0' <= line[j] && line[j] <= '9')) {
+				j++
+			}
+			if j == i {
+				return nil, 0, fmt.Errorf("invalid toml key: '%s'", line)
+			}
+			parts = append(parts, line[i:j])
+			i = j
+		}
+
+		for i < len(line) && (line[i] == ' ' || line[i] == '\t') {
+			i++
+		}
+		if i >= len(line) || line[i] != '.' {
+			return parts, i, nil
+		}
+		i++
+	}
+}
+
+func tomlKey(keys []string) string {
+	var parts []string
+	for _, k := range keys {
+		if regexpTOMLBareKey.MatchString(k) {
+			parts = append(parts, k)
+			continue
+		}
+
+		s, _ := jsonString(k)
+		parts = append(parts, s)
+	}
+
+	return strings.Join(parts, ".")
+}
+
+func tomlScalar(value interface{}, quote byte) (string, error) {
+	switch v := value.(type) {
+	case string:
+		if quote == '\'' && !strings.ContainsAny(v, "'\n") {
+			return "'" + v + "'", nil
+		}
+		return jsonString(v)
+	case float32, float64:
+		s := strconv.FormatFloat(reflect.ValueOf(v).Float(), 'f', -1, 64)
+		if !strings.ContainsAny(s, ".eEni") {
+			s += ".0"
+		}
+		return s, nil
+	default:
+		return fmt.Sprintf("%v", v), nil
+	}
+}
+
+// tomlOpened checks whether the value, which starts at start, continues to
+// the next lines, like multi-line strings and arrays.
+func tomlOpened(line string, start int) (string, bool) {
+	v := line[start:]
+	for _, q := range []string{`"""`, `'''`} {
+		if strings.HasPrefix(v, q) && !strings.Contains(v[3:], q) {
+			return q, true
+		}
+	}
+
+	if strings.HasPrefix(v, "[") && strings.Count(v, "[") > strings.Count(v, "]") {
+		return "]", true
+	}
+
+	return "", false
+}
+
+func equalKeys(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func updateTOML(b []byte, keys []string, value interface{}) ([]byte, error) {
+	lines := splitLines(b)
+
+	var table []string
+	var arrayTable bool
+	var closing string
+	var depth int
+	tableEnds := map[string]int{}
+
+	for i, line := range lines {
+		t := strings.TrimSpace(line)
+		if len(closing) > 0 {
+			// the multi-line value ends at the closing line, so the new key is
+			// inserted after it
+			if !arrayTable {
+				tableEnds[strings.Join(table, "\x00")] = i
+			}
+
+			if closing == "]" {
+				depth += strings.Count(t, "[") - strings.Count(t, "]")
+				if depth < 1 {
+					closing = ""
+				}
+			} else if strings.Contains(t, closing) {
+				closing = ""
+			}
+			continue
+		}
+		if len(t) < 1 || t[0] == '#' {
+			continue
+		}
+
+		if strings.HasPrefix(t, "[[") {
+			arrayTable = true
+			continue
+		} else if t[0] == '[' {
+			start := strings.Index(line, "[") + 1
+			parts, end, err := tomlKeyParts(line, start)
+			if err != nil {
+				return nil, err
+			} else if end >= len(line) || line[end] != ']' {
+				return nil, fmt.Errorf("invalid toml table: '%s'", line)
+			}
+
+			arrayTable = false
+			table = parts
+			tableEnds[strings.Join(table, "\x00")] = i
+			continue
+		} else if arrayTable {
+			continue
+		}
+
+		parts, end, err := tomlKeyParts(line, 0)
+		if err != nil {
+			return nil, err
+		} else if end >= len(line) || line[end] != '=' {
+			return nil, fmt.Errorf("invalid toml key: '%s'", line)
+		}
+		tableEnds[strings.Join(table, "\x00")] = i
+
+		start := end + 1
+		for start < len(line) && (line[start] == ' ' || line[start] == '\t') {
+			start++
+		}
+
+		var opened bool
+		closing, opened = tomlOpened(line, start)
+		if closing == "]" {
+			depth = strings.Count(line[start:], "[") - strings.Count(line[start:], "]")
+		}
+
+		path := append(append([]string{}, table...), parts...)
+		switch {
+		case equalKeys(path, keys):
+			if opened || strings.HasPrefix(line[start:], "{") || strings.HasPrefix(line[start:], "[") {
+				return nil, fmt.Errorf("'%s' is not a scalar", strings.Join(keys, "."))
+			}
+
+			end, err := scalarEnd(line, start, start < len(line) && line[start] == '"')
+			if err != nil {
+				return nil, err
+			}
+
+			var quote byte
+			if start < len(line) {
+				quote = line[start]
+			}
+			text, err := tomlScalar(value, quote)
+			if err != nil {
+				return nil, err
+			}
+
+			lines[i] = line[:start] + text + line[end:]
+			return validateTOML(joinLines(lines), keys)
+		case len(path) < len(keys) && equalKeys(path, keys[:len(path)]):
+			return nil, fmt.Errorf("'%s' is not a table", strings.Join(path, "."))
+		}
+	}
+
+	text, err := tomlScalar(value, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	inserted := tomlKey(keys[len(keys)-1:]) + " = " + text
+	if at, found := tableEnds[strings.Join(keys[:len(keys)-1], "\x00")]; found {
+		return validateTOML(joinLines(insertLines(lines, at+1, inserted)), keys)
+	}
+
+	if len(lines) > 0 {
+		lines = append(lines, "")
+	}
+	lines = append(lines, "["+tomlKey(keys[:len(keys)-1])+"]", inserted)
+
+	return validateTOML(joinLines(lines), keys)
+}
+
+func validateTOML(b []byte, keys []string) ([]byte, error) {
+	t, err := toml.LoadBytes(b)
+	if err != nil {
+		return nil, err
+	}
+	if !t.HasPath(keys) {
+		return nil, fmt.Errorf("failed to update toml: '%s'", strings.Join(keys, "."))
+	}
+
+	return b, nil
+}
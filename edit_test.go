@@ -0,0 +1,311 @@
+package cvc
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type testEditLogConfig struct {
+	BaseGroup
+
+	File  string
+	Level string
+}
+
+func (l *testEditLogConfig) ParseLevel(input string) (string, error) {
+	switch s := strings.ToLower(input); s {
+	case "debug", "error":
+		return s, nil
+	default:
+		return "", os.ErrInvalid
+	}
+}
+
+type testEditConfig struct {
+	BaseGroup
+
+	Port    int
+	Timeout time.Duration
+	Log     *testEditLogConfig
+}
+
+func (c *testEditConfig) ValidatePort() error {
+	if c.Port < 1 {
+		return errors.New("invalid port")
+	}
+
+	return nil
+}
+
+type testEdit struct {
+	suite.Suite
+	dir string
+}
+
+func (t *testEdit) SetupTest() {
+	dir, err := ioutil.TempDir("", "cvc")
+	t.NoError(err)
+	t.dir = dir
+}
+
+func (t *testEdit) TearDownTest() {
+	os.RemoveAll(t.dir)
+}
+
+func (t *testEdit) newManager() *Manager {
+	config := &testEditConfig{
+		Port: 80,
+		Log:  &testEditLogConfig{File: "naru.log", Level: "debug"},
+	}
+
+	return newTestManager(t.T(), config, nil, nil)
+}
+
+func (t *testEdit) update(name, body, key string, value interface{}) (string, error) {
+	f := filepath.Join(t.dir, name)
+	t.NoError(ioutil.WriteFile(f, []byte(body), 0644))
+
+	if err := t.newManager().UpdateFile(f, key, value); err != nil {
+		return "", err
+	}
+
+	b, err := ioutil.ReadFile(f)
+	t.NoError(err)
+
+	return string(b), nil
+}
+
+func (t *testEdit) TestYAMLReplace() {
+	body := `# naru config
+naru:
+    # listen port
+    port: 80   # default is 80
+    log:
+        file: "naru.log"
+        level: debug # or error
+other: 1
+`
+	s, err := t.update("config.yml", body, "port", 8080)
+	t.NoError(err)
+	t.Equal(`# naru config
+naru:
+    # listen port
+    port: 8080   # default is 80
+    log:
+        file: "naru.log"
+        level: debug # or error
+other: 1
+`, s)
+
+	s, err = t.update("config.yml", body, "log.file", "/var/log/naru.log")
+	t.NoError(err)
+	t.Contains(s, "        file: \"/var/log/naru.log\"\n")
+
+	s, err = t.update("config.yml", body, "log.level", "error")
+	t.NoError(err)
+	t.Contains(s, "        level: error # or error\n")
+
+	s, err = t.update("config.yml", body, "timeout", 3*time.Second)
+	t.NoError(err)
+	t.Equal(`# naru config
+naru:
+    # listen port
+    port: 80   # default is 80
+    log:
+        file: "naru.log"
+        level: debug # or error
+    timeout: 3s
+other: 1
+`, s)
+}
+
+func (t *testEdit) TestYAMLInsertGroup() {
+	body := `naru:
+  port: 80 # port
+`
+	s, err := t.update("config.yml", body, "log.level", "error")
+	t.NoError(err)
+	t.Equal(`naru:
+  port: 80 # port
+  log:
+    level: error
+`, s)
+
+	s, err = t.update("config.yml", "# empty\nnaru:\n", "log.level", "error")
+	t.NoError(err)
+	t.Equal("# empty\nnaru:\n  log:\n    level: error\n", s)
+
+	s, err = t.update("config.yml", "", "port", 1)
+	t.NoError(err)
+	t.Equal("naru:\n  port: 1\n", s)
+
+	s, err = t.update("config.yml", "naru:\n  port:\n", "port", 1)
+	t.NoError(err)
+	t.Equal("naru:\n  port: 1\n", s)
+}
+
+func (t *testEdit) TestYAMLNotScalar() {
+	_, err := t.update("config.yml", "naru:\n  port:\n    a: 1\n", "port", 1)
+	t.Error(err)
+
+	_, err = t.update("config.yml", "naru: {port: 1}\n", "port", 1)
+	t.Error(err)
+}
+
+func (t *testEdit) TestTOMLReplace() {
+	body := `# naru config
+[naru]
+port = 80 # listen port
+
+[naru.log]
+# output
+file = 'naru.log'
+level = "debug"
+`
+	s, err := t.update("config.toml", body, "port", 8080)
+	t.NoError(err)
+	t.Equal(`# naru config
+[naru]
+port = 8080 # listen port
+
+[naru.log]
+# output
+file = 'naru.log'
+level = "debug"
+`, s)
+
+	s, err = t.update("config.toml", body, "log.file", "/naru.log")
+	t.NoError(err)
+	t.Contains(s, "file = '/naru.log'\n")
+
+	s, err = t.update("config.toml", body, "log.level", "error")
+	t.NoError(err)
+	t.Contains(s, "level = \"error\"\n")
+}
+
+func (t *testEdit) TestTOMLInsert() {
+	body := `[naru]
+port = 80 # listen port
+
+[other]
+a = 1
+`
+	s, err := t.update("config.toml", body, "timeout", time.Second)
+	t.NoError(err)
+	t.Equal(`[naru]
+port = 80 # listen port
+timeout = "1s"
+
+[other]
+a = 1
+`, s)
+
+	s, err = t.update("config.toml", body, "log.level", "error")
+	t.NoError(err)
+	t.Equal(body+"\n[naru.log]\nlevel = \"error\"\n", s)
+}
+
+func (t *testEdit) TestTOMLInsertAfterMultiLine() {
+	body := `[naru]
+hosts = [
+  ["a"],
+  ["b", "c"],
+]
+description = """
+naru
+"""
+`
+	s, err := t.update("config.toml", body, "timeout", time.Second)
+	t.NoError(err)
+	t.Equal(body+"timeout = \"1s\"\n", s)
+
+	body = `[naru]
+description = """
+naru
+"""
+
+[other]
+a = 1
+`
+	s, err = t.update("config.toml", body, "port", 8080)
+	t.NoError(err)
+	t.Equal(`[naru]
+description = """
+naru
+"""
+port = 8080
+
+[other]
+a = 1
+`, s)
+}
+
+func (t *testEdit) TestTOMLNotTable() {
+	_, err := t.update("config.toml", "[naru]\nlog = { level = \"debug\" }\n", "log.level", "error")
+	t.Error(err)
+}
+
+func (t *testEdit) TestParse() {
+	body := "naru:\n  log:\n    level: debug\n"
+	_, err := t.update("config.yml", body, "log.level", "unknown")
+	t.Equal(os.ErrInvalid, err)
+
+	_, err = t.update("config.yml", body, "port", "eighty")
+	t.Error(err)
+
+	_, err = t.update("config.yml", body, "unknown", 1)
+	t.True(ErrorKeyNotFound.Equal(err))
+
+	_, err = t.update("config.json", "{}", "port", 1)
+	t.Error(err)
+
+	b, err := ioutil.ReadFile(filepath.Join(t.dir, "config.yml"))
+	t.NoError(err)
+	t.Equal(body, string(b))
+}
+
+func (t *testEdit) TestParsedValue() {
+	body := "naru:\n  port: 80\n  log:\n    level: debug\n"
+
+	s, err := t.update("config.yml", body, "log.level", "ERROR")
+	t.NoError(err)
+	t.Contains(s, "    level: error\n")
+
+	s, err = t.update("config.yml", body, "port", "8080")
+	t.NoError(err)
+	t.Contains(s, "  port: 8080\n")
+
+	_, err = t.update("config.yml", body, "port", 0)
+	var verr *ValidationError
+	t.True(errors.As(err, &verr))
+	t.Equal("port", verr.Key)
+
+	b, err := ioutil.ReadFile(filepath.Join(t.dir, "config.yml"))
+	t.NoError(err)
+	t.Equal(body, string(b))
+}
+
+func (t *testEdit) TestMerge() {
+	f := filepath.Join(t.dir, "config.yml")
+	t.NoError(ioutil.WriteFile(f, []byte("naru:\n  port: 80\n"), 0644))
+
+	t.NoError(t.newManager().UpdateFile(f, "log.level", "error"))
+
+	manager := t.newManager()
+	t.NoError(manager.SetViperConfigFile(f))
+	_, err := manager.Merge()
+	t.NoError(err)
+
+	t.Equal("error", MustGet[string](manager, "log.level"))
+}
+
+func TestEdit(t *testing.T) {
+	suite.Run(t, new(testEdit))
+}
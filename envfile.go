@@ -0,0 +1,192 @@
+package cvc
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cast"
+)
+
+// envFileSettings reads the env file, the `KEY=value` lines; the key is the
+// env name of the item, like `NARU_LOG_LEVEL`. The lists and maps of groups
+// grow by the keys like the envs, like `NARU_UPSTREAMS_0_HOST`.
+func (m *Manager) envFileSettings(r io.Reader) (map[string]interface{}, error) {
+	var keys []string
+	values := map[string]string{}
+
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		l := strings.TrimSpace(s.Text())
+		if len(l) < 1 || strings.HasPrefix(l, "#") {
+			continue
+		}
+
+		kv := strings.SplitN(strings.TrimPrefix(l, "export "), "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid line %d of env file", n)
+		}
+
+		k := strings.TrimSpace(kv[0])
+		v, err := envFileValue(strings.TrimSpace(kv[1]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of %s: %w", k, err)
+		}
+
+		if _, found := values[k]; !found {
+			keys = append(keys, k)
+		}
+		values[k] = v
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	m.growFromEnvFile(values)
+
+	items := map[string]*Item{}
+	for _, item := range m.m {
+		if item.IsGroup {
+			continue
+		}
+		if env := m.EnvName(item); len(env) > 0 {
+			items[env] = item
+		}
+	}
+
+	c := map[string]interface{}{}
+	for _, k := range keys {
+		item, found := items[k]
+		if !found {
+			return nil, &UnknownKeyError{Key: k, Source: SourceConfig}
+		}
+
+		setConfigValue(c, strings.Split(item.FullName(), "."), values[k])
+	}
+
+	return map[string]interface{}{m.group: c}, nil
+}
+
+// growFromEnvFile grows the lists and maps of groups by the values of env
+// file instead of the envs.
+func (m *Manager) growFromEnvFile(values map[string]string) {
+	lookup, environ := m.envLookupFunc, m.environFunc
+	defer func() {
+		m.envLookupFunc, m.environFunc = lookup, environ
+	}()
+
+	m.envLookupFunc = func(k string) (string, bool) {
+		v, found := values[k]
+		return v, found
+	}
+	m.environFunc = func() []string {
+		var envs []string
+		for k, v := range values {
+			envs = append(envs, k+"="+v)
+		}
+		return envs
+	}
+
+	m.growFromEnv()
+}
+
+func envFileValue(v string) (string, error) {
+	var q string
+	switch {
+	case strings.HasPrefix(v, `"`):
+		p, err := strconv.QuotedPrefix(v)
+		if err != nil {
+			return "", err
+		}
+		q = p
+	case strings.HasPrefix(v, "'"):
+		i := strings.Index(v[1:], "'")
+		if i < 0 {
+			return "", fmt.Errorf("unterminated quote")
+		}
+		q = v[:i+2]
+	default:
+		if i := strings.Index(v, " #"); i >= 0 {
+			v = strings.TrimSpace(v[:i])
+		}
+		return v, nil
+	}
+
+	if rest := strings.TrimSpace(v[len(q):]); len(rest) > 0 && !strings.HasPrefix(rest, "#") {
+		return "", fmt.Errorf("unexpected characters after quote")
+	}
+
+	if q[0] == '\'' {
+		return q[1 : len(q)-1], nil
+	}
+
+	return strconv.Unquote(q)
+}
+
+// encodeConfig encodes the config like encodeConfig; the env format is
+// encoded with the env names of the items.
+func (m *Manager) encodeConfig(format string, c map[string]interface{}) ([]byte, error) {
+	if !isEnvFormat(strings.ToLower(format)) {
+		return encodeConfig(format, c)
+	}
+
+	flat := map[string]interface{}{}
+	flattenConfigValues("", cast.ToStringMap(c[m.group]), flat)
+
+	var lines []string
+	for k, v := range flat {
+		item, _, found := m.configItem(k)
+		if !found {
+			if i, found := m.interfaceKey(k); found {
+				return nil, fmt.Errorf("the value of '%s' can not be written in env", i)
+			}
+			continue
+		}
+		if item.IsGroup {
+			continue
+		}
+
+		env := m.EnvName(item)
+		if len(env) < 1 {
+			continue
+		}
+
+		s := cast.ToString(v)
+		if strings.ContainsAny(s, " \t\n\"'#=\\") {
+			s = strconv.Quote(s)
+		}
+		lines = append(lines, env+"="+s)
+	}
+
+	sort.Strings(lines)
+
+	return []byte(strings.Join(lines, "\n") + "\n"), nil
+}
+
+// flattenConfigValues flattens the config like flattenConfig, and the
+// sequences are also flattened by the index, like `upstreams.0.host`.
+func flattenConfigValues(prefix string, c map[string]interface{}, flat map[string]interface{}) {
+	for k, v := range c {
+		n := k
+		if len(prefix) > 0 {
+			n = prefix + "." + k
+		}
+
+		switch t := v.(type) {
+		case map[string]interface{}, map[interface{}]interface{}:
+			flattenConfigValues(n, cast.ToStringMap(t), flat)
+		case []interface{}:
+			s := map[string]interface{}{}
+			for i, e := range t {
+				s[strconv.Itoa(i)] = e
+			}
+			flattenConfigValues(n, s, flat)
+		default:
+			flat[n] = v
+		}
+	}
+}
@@ -6,19 +6,35 @@ import (
 	"sync"
 )
 
+// The error codes are stable; the new code must be appended.
 const (
 	_ = iota + 1
 	ErrorMethodNotFoundCode
 	ErrorInvalidMethodCode
+	ErrorKeyNotFoundCode
+	ErrorNotAssignableCode
+	ErrorUnknownKeyCode
+	ErrorParseCode
+	ErrorValidationCode
+	ErrorSourceCode
 )
 
 var (
 	ErrorMethodNotFound, _ = NewError(ErrorMethodNotFoundCode, "method not found")
 	ErrorInvalidMethod, _  = NewError(ErrorInvalidMethodCode, "invalid method found")
+	ErrorKeyNotFound, _    = NewError(ErrorKeyNotFoundCode, "key not found")
+	ErrorNotAssignable, _  = NewError(ErrorNotAssignableCode, "value is not assignable")
+	ErrorUnknownKey, _     = NewError(ErrorUnknownKeyCode, "unknown key found")
+	ErrorParse, _          = NewError(ErrorParseCode, "failed to parse")
+	ErrorValidation, _     = NewError(ErrorValidationCode, "invalid value")
+	ErrorSource, _         = NewError(ErrorSourceCode, "failed to read source")
 )
 
+// Error is the error of the stable code. The copies of Error share the lock,
+// so Error can be used as value; Lock, Unlock, RLock and RUnlock lock the
+// shared lock, and they do nothing for the Error without NewError.
 type Error struct {
-	sync.RWMutex
+	l       *sync.RWMutex
 	Code    int
 	Message string
 	Extra   map[string]interface{}
@@ -35,44 +51,69 @@ func NewError(code int, message string, extras ...interface{}) (Error, error) {
 	}
 
 	return Error{
+		l:       &sync.RWMutex{},
 		Code:    code,
 		Message: message,
 		Extra:   extra,
 	}, nil
 }
 
-func (e Error) Error() string {
-	e.RLock()
-	defer e.RUnlock()
+func (e Error) Lock() {
+	if e.l != nil {
+		e.l.Lock()
+	}
+}
 
-	b, _ := json.Marshal(e)
-	return string(b)
+func (e Error) Unlock() {
+	if e.l != nil {
+		e.l.Unlock()
+	}
 }
 
-func (e Error) JSON() (o map[string]interface{}) {
+func (e Error) RLock() {
+	if e.l != nil {
+		e.l.RLock()
+	}
+}
+
+func (e Error) RUnlock() {
+	if e.l != nil {
+		e.l.RUnlock()
+	}
+}
+
+func (e Error) marshal() []byte {
 	e.RLock()
 	defer e.RUnlock()
 
 	b, _ := json.Marshal(e)
-	json.Unmarshal(b, &o)
+
+	return b
+}
+
+func (e Error) Error() string {
+	return string(e.marshal())
+}
+
+func (e Error) JSON() (o map[string]interface{}) {
+	json.Unmarshal(e.marshal(), &o)
 
 	return
 }
 
+// Equal checks the code of error; the typed errors like *ParseError are equal
+// to the Error of their code.
 func (e *Error) Equal(i error) bool {
-	e.RLock()
-	defer e.RUnlock()
-
-	o, found := i.(*Error)
-	if !found {
-		b, found := i.(Error)
-		if !found {
-			return false
-		}
-		o = &b
+	if code, found := errorCode(i); found {
+		return e.Code == code
 	}
 
-	return e.Code == o.Code
+	return false
+}
+
+// Is makes errors.Is(err, ErrorKeyNotFound) work with the cloned errors.
+func (e Error) Is(target error) bool {
+	return isErrorCode(target, e.Code)
 }
 
 func (e Error) Clone() *Error {
@@ -85,6 +126,7 @@ func (e Error) Clone() *Error {
 	}
 
 	return &Error{
+		l:       &sync.RWMutex{},
 		Code:    e.Code,
 		Message: e.Message,
 		Extra:   extra,
@@ -92,9 +134,148 @@ func (e Error) Clone() *Error {
 }
 
 func (e *Error) Set(k string, v interface{}) *Error {
+	if e.l == nil {
+		e.l = &sync.RWMutex{}
+	}
+
 	e.Lock()
 	defer e.Unlock()
 
+	if e.Extra == nil {
+		e.Extra = map[string]interface{}{}
+	}
 	e.Extra[k] = v
+
 	return e
 }
+
+// The sources of the typed errors.
+const (
+	SourceEnv       = "env"
+	SourceConfig    = "config"
+	SourceFlag      = "flag"
+	SourceDefault   = "default"
+	SourceValidator = "validator"
+)
+
+// UnknownKeyError is returned when the config source has the key, which is
+// not in the config.
+type UnknownKeyError struct {
+	Key    string
+	Source string
+}
+
+func (e *UnknownKeyError) Error() string {
+	return fmt.Sprintf("unknown key found in %s: '%s'", e.Source, e.Key)
+}
+
+func (e *UnknownKeyError) Code() int {
+	return ErrorUnknownKeyCode
+}
+
+func (e *UnknownKeyError) Is(target error) bool {
+	return isErrorCode(target, e.Code())
+}
+
+// ParseError is returned when the input of the source can not be parsed.
+type ParseError struct {
+	Key    string
+	Source string
+	Input  interface{}
+	Err    error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("failed to parse '%s' from %s, %v: %v", e.Key, e.Source, e.Input, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+func (e *ParseError) Code() int {
+	return ErrorParseCode
+}
+
+func (e *ParseError) Is(target error) bool {
+	return isErrorCode(target, e.Code())
+}
+
+// ValidationError is returned when the item is invalid after merging the
+// source; Key is the config key, which is invalid, and Input is the value of
+// it.
+type ValidationError struct {
+	Key    string
+	Source string
+	Input  interface{}
+	Err    error
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid '%s': %v", e.Key, e.Err)
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+func (e *ValidationError) Code() int {
+	return ErrorValidationCode
+}
+
+func (e *ValidationError) Is(target error) bool {
+	return isErrorCode(target, e.Code())
+}
+
+// SourceError is returned when the source itself is invalid, like the broken
+// config file or the conflicted aliases.
+type SourceError struct {
+	Key    string
+	Source string
+	Input  interface{}
+	Err    error
+}
+
+func (e *SourceError) Error() string {
+	if len(e.Key) < 1 {
+		return fmt.Sprintf("invalid %s: %v", e.Source, e.Err)
+	}
+
+	return fmt.Sprintf("invalid %s, '%s': %v", e.Source, e.Key, e.Err)
+}
+
+func (e *SourceError) Unwrap() error {
+	return e.Err
+}
+
+func (e *SourceError) Code() int {
+	return ErrorSourceCode
+}
+
+func (e *SourceError) Is(target error) bool {
+	return isErrorCode(target, e.Code())
+}
+
+func isErrorCode(target error, code int) bool {
+	switch o := target.(type) {
+	case Error:
+		return o.Code == code
+	case *Error:
+		return o.Code == code
+	default:
+		return false
+	}
+}
+
+func errorCode(err error) (int, bool) {
+	switch o := err.(type) {
+	case Error:
+		return o.Code, true
+	case *Error:
+		return o.Code, true
+	case interface{ Code() int }:
+		return o.Code(), true
+	default:
+		return 0, false
+	}
+}
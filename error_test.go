@@ -0,0 +1,185 @@
+package cvc
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type testError struct {
+	suite.Suite
+}
+
+func (t *testError) newManager(args ...string) (*testConfig, *Manager) {
+	config := &testConfig{A: 1, B: "b"}
+
+	return config, newTestManager(t.T(), config, nil, args)
+}
+
+func (t *testError) TestClone() {
+	err := ErrorKeyNotFound.Clone().Set("key", "a")
+
+	t.True(errors.Is(err, ErrorKeyNotFound))
+	t.False(errors.Is(err, ErrorNotAssignable))
+	t.True(ErrorKeyNotFound.Equal(err))
+	t.Equal(map[string]interface{}{"key": "a"}, err.JSON()["Extra"])
+}
+
+func (t *testError) TestUnknownKey() {
+	_, manager := t.newManager()
+	manager.SetViperConfig("yml", []byte(`
+naru:
+  c: 1
+`))
+
+	_, err := manager.Merge()
+	t.True(errors.Is(err, ErrorUnknownKey))
+	t.True(ErrorUnknownKey.Equal(err))
+
+	var e *UnknownKeyError
+	t.True(errors.As(err, &e))
+	t.Equal("naru.c", e.Key)
+	t.Equal(SourceConfig, e.Source)
+}
+
+func (t *testError) TestParse() {
+	config, manager := t.newManager()
+	manager.SetEnvLookupFunc(func(s string) (string, bool) {
+		if s == "NARU_A" {
+			return "a", true
+		}
+		return "", false
+	})
+
+	_, err := manager.Merge()
+	t.True(errors.Is(err, ErrorParse))
+	t.False(errors.Is(err, ErrorSource))
+
+	var e *ParseError
+	t.True(errors.As(err, &e))
+	t.Equal("a", e.Key)
+	t.Equal(SourceEnv, e.Source)
+	t.Equal("a", e.Input)
+	t.Error(errors.Unwrap(err))
+	t.Equal(1, config.A)
+}
+
+func (t *testError) TestValidation() {
+	config, manager := t.newManager("--b", "c")
+	config.validate = func() error {
+		if config.B == "c" {
+			return errors.New("c is not allowed")
+		}
+		return nil
+	}
+
+	_, err := manager.Merge()
+	t.True(errors.Is(err, ErrorValidation))
+
+	var e *ValidationError
+	t.True(errors.As(err, &e))
+	t.Equal(SourceFlag, e.Source)
+	t.Equal(config, e.Input)
+	t.EqualError(e.Err, "c is not allowed")
+}
+
+func (t *testError) TestSource() {
+	_, manager := t.newManager()
+	manager.SetViperConfig("yml", []byte(`
+naru:
+  a: [
+`))
+
+	_, err := manager.Merge()
+	t.True(errors.Is(err, ErrorSource))
+
+	var e *SourceError
+	t.True(errors.As(err, &e))
+	t.Equal(SourceConfig, e.Source)
+}
+
+func (t *testError) TestValue() {
+	var err error = ErrorKeyNotFound
+
+	t.True(errors.Is(err, ErrorKeyNotFound))
+	t.True(ErrorKeyNotFound.Equal(err))
+	t.Equal(float64(ErrorKeyNotFoundCode), ErrorKeyNotFound.JSON()["Code"])
+	t.Equal(map[string]interface{}{}, ErrorKeyNotFound.JSON()["Extra"])
+
+	e := Error{Code: ErrorKeyNotFoundCode}
+	t.True(errors.Is(e.Clone().Set("key", "a"), ErrorKeyNotFound))
+	t.True(errors.Is(e.Set("key", "b"), ErrorKeyNotFound))
+}
+
+func (t *testError) TestLock() {
+	e := ErrorKeyNotFound.Clone()
+
+	e.Lock()
+	locked := make(chan struct{})
+	go func() {
+		c := *e // the copy shares the lock
+		c.RLock()
+		defer c.RUnlock()
+
+		close(locked)
+	}()
+
+	select {
+	case <-locked:
+		t.Fail("copy is not locked")
+	case <-time.After(time.Millisecond * 100):
+	}
+	e.Unlock()
+	<-locked
+
+	var z Error
+	z.Lock()
+	z.Unlock()
+	t.Equal("a", z.Set("key", "a").Extra["key"])
+}
+
+type testErrorHookConfig struct {
+	C string
+	D string
+}
+
+func (t *testErrorHookConfig) ParseEnvC(string) (int, error) {
+	return 1, nil
+}
+
+func (t *testErrorHookConfig) ParseD(string) (int, error) {
+	return 1, nil
+}
+
+func (t *testError) TestEnvNotAssignable() {
+	manager := newTestManager(t.T(), &testErrorHookConfig{}, map[string]string{"NARU_C": "c"}, []string{})
+
+	n, err := manager.Merge()
+	t.Equal("NARU_C", n)
+	t.True(errors.Is(err, ErrorParse))
+	t.True(errors.Is(err, ErrorNotAssignable))
+
+	var e *ParseError
+	t.True(errors.As(err, &e))
+	t.Equal(SourceEnv, e.Source)
+	t.Equal("c", e.Input)
+}
+
+func (t *testError) TestFlagNotAssignable() {
+	manager := newTestManager(t.T(), &testErrorHookConfig{}, nil, []string{"--d", "d"})
+
+	n, err := manager.Merge()
+	t.Equal("d", n)
+	t.True(errors.Is(err, ErrorParse))
+	t.True(errors.Is(err, ErrorNotAssignable))
+
+	var e *ParseError
+	t.True(errors.As(err, &e))
+	t.Equal(SourceFlag, e.Source)
+}
+
+func TestError(t *testing.T) {
+	suite.Run(t, new(testError))
+}
@@ -1,3 +1,5 @@
+//go:generate go run github.com/spikeekips/cvc/cmd/cvcgen -type Config
+
 package main
 
 import (
@@ -24,8 +26,8 @@ type Config struct {
 	cvc.BaseGroup
 
 	Verbose   bool   `flag-help:"verbose"`
-	SetString string `flag-help:"set integer"`
-	SetInt    int    `flag-help:"set string"`
+	SetString string `flag-help:"set integer" default:"find me"`
+	SetInt    int    `flag-help:"set string" default:"100"`
 
 	Log *LogConfig
 }
@@ -38,9 +40,9 @@ func (c *Config) ParseEnvSetInt(s string) (int, error) {
 type LogConfig struct {
 	cvc.BaseGroup
 
-	File   string   `flag-help:"log output file"`
-	Level  LogLevel `flag-help:"log format {terminal json}"`
-	Format string   `flag-help:"log level {debug error warn crit}"`
+	File   string   `flag-help:"log output file" default:"naru.log"`
+	Level  LogLevel `flag-help:"log format {terminal json}" default:"debug"`
+	Format string   `flag-help:"log level {debug error warn crit}" default:"terminal"`
 }
 
 func (l *LogConfig) ParseLevel(input string) (LogLevel, error) {
@@ -78,15 +80,7 @@ func init() {
 	}
 
 	vp = viper.New()
-	config := &Config{
-		SetString: "find me",
-		SetInt:    100,
-		Log: &LogConfig{
-			File:   "naru.log",
-			Level:  LogLevel{Name: "debug"},
-			Format: "terminal",
-		},
-	}
+	config := &Config{}
 	manager = cvc.NewManager("naru", config, cmd, vp)
 }
 
@@ -0,0 +1,27 @@
+// Code generated by cvcgen; DO NOT EDIT.
+
+package main
+
+import "github.com/spikeekips/cvc"
+
+func init() {
+	cvc.RegisterBinding[LogLevel](map[string]cvc.BoundMethod{})
+	cvc.RegisterBinding[*LogConfig](map[string]cvc.BoundMethod{
+		"Merge":      cvc.BindError((*LogConfig).Merge),
+		"ParseLevel": cvc.BindParse((*LogConfig).ParseLevel),
+		"Validate":   cvc.BindError((*LogConfig).Validate),
+	})
+	cvc.RegisterBinding[*Config](map[string]cvc.BoundMethod{
+		"Merge":          cvc.BindError((*Config).Merge),
+		"ParseEnvSetInt": cvc.BindParse((*Config).ParseEnvSetInt),
+		"Validate":       cvc.BindError((*Config).Validate),
+	})
+}
+
+func _(c *LogConfig) {
+	_ = c.Level
+}
+
+func _(c *Config) {
+	_ = c.SetInt
+}
@@ -0,0 +1,399 @@
+package cvc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/hcl"
+	"github.com/hashicorp/hcl/hcl/printer"
+	"github.com/magiconair/properties"
+	toml "github.com/pelletier/go-toml"
+	"github.com/spf13/cast"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// the config formats, which are not supported by viper; jsonc and json5 are
+// read as JSON5, and env is the flat `KEY=value` lines of the env names.
+var extraFormats = []string{"jsonc", "json5", "env", "dotenv"}
+
+var (
+	regexpEnvLine   = regexp.MustCompile(`^(export\s+)?[A-Z_][A-Z0-9_]*=`)
+	regexpTOMLTable = regexp.MustCompile(`^\[\[?[^\[\]]+\]\]?$`)
+	regexpHCLBlock  = regexp.MustCompile(`^[\w.-]+(\s+"[^"]*")*\s*\{$`)
+	regexpHCLIdent  = regexp.MustCompile(`^[A-Za-z_][\w.-]*$`)
+	regexpPropsLine = regexp.MustCompile(`^[^\s=:]+\s*[=:]`)
+)
+
+func isEnvFormat(format string) bool {
+	return format == "env" || format == "dotenv"
+}
+
+// sniffFormat detects the format of config by the content; the empty string
+// is returned for the unknown content.
+func sniffFormat(b []byte) string {
+	var lines []string
+	for _, l := range strings.Split(string(b), "\n") {
+		l = strings.TrimSpace(l)
+		if len(l) < 1 || strings.HasPrefix(l, "#") || strings.HasPrefix(l, "//") {
+			continue
+		}
+		lines = append(lines, l)
+	}
+
+	if len(lines) < 1 {
+		return ""
+	}
+
+	switch {
+	case strings.HasPrefix(lines[0], "{"):
+		if json.Valid(b) {
+			return "json"
+		}
+		if _, err := parseJSON5(b); err == nil {
+			return "json5"
+		}
+		return ""
+	case matchAll(regexpEnvLine, lines):
+		return "env"
+	case matchAny(regexpTOMLTable, lines):
+		if _, err := toml.LoadBytes(b); err == nil {
+			return "toml"
+		}
+	case matchAny(regexpHCLBlock, lines):
+		if _, err := hcl.ParseBytes(b); err == nil {
+			return "hcl"
+		}
+	}
+
+	var m map[string]interface{}
+	if err := yaml.Unmarshal(b, &m); err == nil && len(m) > 0 {
+		return "yaml"
+	}
+
+	// toml without table is like properties, but the values are typed
+	if _, err := toml.LoadBytes(b); err == nil {
+		return "toml"
+	}
+
+	if matchAll(regexpPropsLine, lines) {
+		return "properties"
+	}
+
+	return ""
+}
+
+func matchAll(r *regexp.Regexp, lines []string) bool {
+	for _, l := range lines {
+		if !r.MatchString(l) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func matchAny(r *regexp.Regexp, lines []string) bool {
+	for _, l := range lines {
+		if r.MatchString(l) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func encodeConfig(format string, c map[string]interface{}) ([]byte, error) {
+	switch strings.ToLower(format) {
+	case "json", "jsonc", "json5":
+		return json.MarshalIndent(c, "", "  ")
+	case "yaml", "yml":
+		return yaml.Marshal(c)
+	case "toml":
+		t, err := toml.TreeFromMap(c)
+		if err != nil {
+			return nil, err
+		}
+		return []byte(t.String()), nil
+	case "hcl":
+		var w bytes.Buffer
+		if err := encodeHCL(&w, "", c); err != nil {
+			return nil, err
+		}
+
+		b, err := printer.Format(w.Bytes())
+		if err != nil {
+			return nil, err
+		}
+		return b, nil
+	case "prop", "props", "properties":
+		flat := map[string]interface{}{}
+		flattenConfig("", c, flat)
+
+		var keys []string
+		for k := range flat {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		p := properties.NewProperties()
+		for _, k := range keys {
+			if _, _, err := p.Set(k, cast.ToString(flat[k])); err != nil {
+				return nil, err
+			}
+		}
+
+		var w bytes.Buffer
+		if _, err := p.WriteComment(&w, "#", properties.UTF8); err != nil {
+			return nil, err
+		}
+		return w.Bytes(), nil
+	default:
+		return nil, fmt.Errorf("unsupported config format: '%s'", format)
+	}
+}
+
+// encodeHCL writes the maps as the blocks, like `log {`, and the lists of maps
+// as the repeated blocks, so they are read back as the groups and the lists of
+// groups.
+func encodeHCL(w *bytes.Buffer, indent string, c map[string]interface{}) error {
+	var keys []string
+	for k := range c {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		name := k
+		if !regexpHCLIdent.MatchString(k) {
+			name = strconv.Quote(k)
+		}
+
+		switch v := c[k].(type) {
+		case nil:
+		case map[string]interface{}, map[interface{}]interface{}:
+			if err := encodeHCLBlock(w, indent, name, cast.ToStringMap(v)); err != nil {
+				return err
+			}
+		default:
+			if l, ok := hclBlocks(v); ok {
+				for _, e := range l {
+					if err := encodeHCLBlock(w, indent, name, e); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+
+			b, err := json.Marshal(v)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(w, "%s%s = %s\n", indent, name, b)
+		}
+	}
+
+	return nil
+}
+
+func encodeHCLBlock(w *bytes.Buffer, indent, name string, c map[string]interface{}) error {
+	fmt.Fprintf(w, "%s%s {\n", indent, name)
+	if err := encodeHCL(w, indent+"  ", c); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "%s}\n", indent)
+
+	return nil
+}
+
+// hclBlocks returns the maps of the non-empty list of maps.
+func hclBlocks(i interface{}) ([]map[string]interface{}, bool) {
+	switch l := i.(type) {
+	case []map[string]interface{}:
+		return l, len(l) > 0
+	case []interface{}:
+		if len(l) < 1 {
+			return nil, false
+		}
+
+		maps := make([]map[string]interface{}, len(l))
+		for j, e := range l {
+			switch e.(type) {
+			case map[string]interface{}, map[interface{}]interface{}:
+				maps[j] = cast.ToStringMap(e)
+			default:
+				return nil, false
+			}
+		}
+		return maps, true
+	default:
+		return nil, false
+	}
+}
+
+// omitJSONKeys removes the members of the objects by the paths, like
+// `Log.Level`, keeping the order of the other members.
+func omitJSONKeys(b []byte, omit map[string]bool) ([]byte, error) {
+	if len(omit) < 1 {
+		return b, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+
+	var w bytes.Buffer
+	if err := copyJSONValue(dec, &w, "", omit); err != nil {
+		return nil, err
+	}
+
+	var o bytes.Buffer
+	if err := json.Indent(&o, w.Bytes(), "", "  "); err != nil {
+		return nil, err
+	}
+
+	return o.Bytes(), nil
+}
+
+func copyJSONValue(dec *json.Decoder, w *bytes.Buffer, path string, omit map[string]bool) error {
+	t, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	d, isDelim := t.(json.Delim)
+	if !isDelim {
+		b, err := json.Marshal(t)
+		if err != nil {
+			return err
+		}
+		w.Write(b)
+		return nil
+	}
+
+	w.WriteString(d.String())
+
+	var n int
+	for i := 0; dec.More(); i++ {
+		name := fmt.Sprintf("%d", i)
+		if d == '{' {
+			k, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			name = k.(string)
+		}
+
+		p := name
+		if len(path) > 0 {
+			p = path + "." + name
+		}
+
+		if omit[p] {
+			var skipped json.RawMessage
+			if err := dec.Decode(&skipped); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if n > 0 {
+			w.WriteByte(',')
+		}
+		n++
+
+		if d == '{' {
+			k, _ := json.Marshal(name)
+			w.Write(k)
+			w.WriteByte(':')
+		}
+
+		if err := copyJSONValue(dec, w, p, omit); err != nil {
+			return err
+		}
+	}
+
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+
+	if d == '{' {
+		w.WriteByte('}')
+	} else {
+		w.WriteByte(']')
+	}
+
+	return nil
+}
+
+func flattenConfig(prefix string, c map[string]interface{}, flat map[string]interface{}) {
+	for k, v := range c {
+		n := k
+		if len(prefix) > 0 {
+			n = prefix + "." + k
+		}
+
+		switch v.(type) {
+		case map[string]interface{}:
+			flattenConfig(n, v.(map[string]interface{}), flat)
+		case map[interface{}]interface{}:
+			flattenConfig(n, cast.ToStringMap(v), flat)
+		default:
+			flat[n] = v
+		}
+	}
+}
+
+func setConfigValue(c map[string]interface{}, keys []string, v interface{}) {
+	for _, k := range keys[:len(keys)-1] {
+		sub, found := c[k].(map[string]interface{})
+		if !found {
+			sub = map[string]interface{}{}
+			c[k] = sub
+		}
+		c = sub
+	}
+
+	c[keys[len(keys)-1]] = v
+}
+
+func writeFileAtomic(path string, b []byte) error {
+	var mode os.FileMode = 0644
+	if fi, err := os.Stat(path); err == nil {
+		mode = fi.Mode()
+	}
+
+	f, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+
+	err = func() error {
+		defer f.Close()
+
+		if _, err := f.Write(b); err != nil {
+			return err
+		}
+		if err := f.Sync(); err != nil {
+			return err
+		}
+		return f.Chmod(mode)
+	}()
+	if err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+
+	if err := os.Rename(f.Name(), path); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,381 @@
+package cvc
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type testFormat struct {
+	suite.Suite
+}
+
+func (t *testFormat) TestSniff() {
+	cases := [][2]string{
+		{"json", `{"naru": {"a": 1}}`},
+		{"json5", "{\n  // comment\n  naru: {a: 1,},\n}"},
+		{"env", "# comment\nNARU_A=1\nexport NARU_LOG_FILE=naru.log\n"},
+		{"toml", "[naru]\na = 1\n"},
+		{"toml", "a = 1\nb = \"x\"\n"},
+		{"hcl", "naru {\n  a = 1\n}\n"},
+		{"yaml", "naru:\n  a: 1\n"},
+		{"properties", "naru.a = 1\nnaru.log.file = naru.log\n"},
+		{"", "naru"},
+	}
+
+	for _, c := range cases {
+		t.Equal(c[0], sniffFormat([]byte(c[1])), c[1])
+	}
+}
+
+func (t *testFormat) TestJSON5() {
+	v, err := parseJSON5([]byte(`{
+  /* block
+     comment */
+  naru: {
+    'a': 'it\'s "a"', // comment
+    b: "http://localhost",
+    c: [1, 2,],
+    file-name: null,
+  },
+}`))
+	t.NoError(err)
+	t.Equal(map[string]interface{}{
+		"naru": map[string]interface{}{
+			"a":         `it's "a"`,
+			"b":         "http://localhost",
+			"c":         []interface{}{float64(1), float64(2)},
+			"file-name": nil,
+		},
+	}, v)
+
+	_, err = parseJSON5([]byte(`{"a": "b`))
+	t.Error(err)
+}
+
+func (t *testFormat) TestJSON5Numbers() {
+	cases := []struct {
+		input    string
+		expected float64
+	}{
+		{"0x1F", 31},
+		{"-0Xff", -255},
+		{"+1", 1},
+		{"+1.5e2", 150},
+		{".5", 0.5},
+		{"5.", 5},
+		{"-.5e-1", -0.05},
+		{"Infinity", math.Inf(1)},
+		{"-Infinity", math.Inf(-1)},
+		{"+Infinity", math.Inf(1)},
+	}
+
+	for _, c := range cases {
+		v, err := parseJSON5([]byte(`{a: ` + c.input + `}`))
+		t.NoError(err, c.input)
+		t.Equal(c.expected, v.(map[string]interface{})["a"], c.input)
+	}
+
+	v, err := parseJSON5([]byte(`{a: NaN}`))
+	t.NoError(err)
+	t.True(math.IsNaN(v.(map[string]interface{})["a"].(float64)))
+
+	for _, s := range []string{"0x", ".", "1e", "+", "Inf"} {
+		_, err := parseJSON5([]byte(`{a: ` + s + `}`))
+		t.Error(err, s)
+	}
+}
+
+func (t *testFormat) TestJSON5Strings() {
+	cases := []struct {
+		input    string
+		expected string
+	}{
+		{"'line \\\n continued'", "line  continued"},
+		{"'crlf \\\r\ncontinued'", "crlf continued"},
+		{`'\x41\u0042\uD83D\uDE00'`, "AB\U0001F600"},
+		{`"\b\f\n\r\t\v\0"`, "\b\f\n\r\t\v\x00"},
+		{`'\a\'\"'`, `a'"`},
+	}
+
+	for _, c := range cases {
+		v, err := parseJSON5([]byte(`{a: ` + c.input + `}`))
+		t.NoError(err, c.input)
+		t.Equal(c.expected, v.(map[string]interface{})["a"], c.input)
+	}
+
+	for _, s := range []string{"'a\nb'", `'\01'`, `'\1'`, `'\xZ1'`, `'\u12'`} {
+		_, err := parseJSON5([]byte(`{a: ` + s + `}`))
+		t.Error(err, s)
+	}
+}
+
+func (t *testFormat) TestReadJSON5Numbers() {
+	manager := (&testManager{}).newWriteManager()
+	t.NoError(manager.SetViperConfig("json5", []byte(`{naru: {a: 0x10, t: '1s',}}`)))
+
+	_, err := manager.Merge()
+	t.NoError(err)
+	t.Equal(16, manager.Config().(*testConfigWrite).A)
+}
+
+func (t *testFormat) TestReadJSON5() {
+	manager := (&testManager{}).newWriteManager()
+	t.NoError(manager.SetViperConfig("jsonc", []byte(`{
+  // the log of naru
+  "naru": {"log": {"level": "error",},},
+}`)))
+
+	_, err := manager.Merge()
+	t.NoError(err)
+
+	var level string
+	t.NoError(manager.GetValue("log.level", &level))
+	t.Equal("error", level)
+}
+
+func (t *testFormat) TestReadEnvFile() {
+	manager := (&testManager{}).newWriteManager()
+	t.NoError(manager.SetViperConfig("", []byte(`
+# env file
+NARU_A=3
+export NARU_T="3s"
+NARU_LOG_LEVEL='error' # comment
+`)))
+
+	_, err := manager.Merge()
+	t.NoError(err)
+
+	config := manager.Config().(*testConfigWrite)
+	t.Equal(3, config.A)
+	t.Equal("3s", config.T.String())
+	t.Equal("error", config.Log.Level)
+}
+
+func (t *testFormat) TestReadEnvFileUnknown() {
+	manager := (&testManager{}).newWriteManager()
+	t.NoError(manager.SetViperConfig("env", []byte("NARU_B=3\n")))
+
+	key, err := manager.Merge()
+	t.Equal("NARU_B", key)
+	t.True(errors.Is(err, ErrorUnknownKey))
+}
+
+func (t *testFormat) TestViperString() {
+	manager := (&testManager{}).newWriteManager()
+
+	s, err := manager.ViperString("env")
+	t.NoError(err)
+	t.Equal("NARU_A=1\nNARU_LOG_FILE=naru.log\nNARU_LOG_LEVEL=debug\nNARU_T=1s", s)
+
+	s, err = manager.ViperString("json5")
+	t.NoError(err)
+	t.True(json.Valid([]byte(s)))
+
+	s, err = manager.ViperString("hcl")
+	t.NoError(err)
+	t.Contains(s, `level = "debug"`)
+}
+
+func (t *testFormat) TestEnvFileRoundTrip() {
+	newManager := func(config interface{}) *Manager {
+		return newTestManager(t.T(), config, nil, []string{})
+	}
+
+	type config struct {
+		BaseGroup
+		Upstreams []testGroupUpstream
+		Databases map[string]*testGroupDatabase
+	}
+
+	a := &config{}
+	manager := newManager(a)
+	t.NoError(manager.SetViperConfig("yml", []byte(`
+naru:
+  upstreams:
+    - host: a
+      port: 8080
+    - host: b
+  databases:
+    primary:
+      dsn: postgres://primary
+`)))
+	_, err := manager.Merge()
+	t.NoError(err)
+
+	s, err := manager.ViperString("env")
+	t.NoError(err)
+	t.Contains(s, "NARU_UPSTREAMS_1_HOST=b")
+	t.Contains(s, "NARU_DATABASES_PRIMARY_DSN=postgres://primary")
+
+	b := &config{}
+	manager = newManager(b)
+	t.NoError(manager.SetViperConfig("env", []byte(s)))
+	_, err = manager.Merge()
+	t.NoError(err)
+	t.Equal(a.Upstreams, b.Upstreams)
+	t.Equal(a.Databases, b.Databases)
+}
+
+func (t *testFormat) TestHCLRoundTrip() {
+	newManager := func(config interface{}) *Manager {
+		return newTestManager(t.T(), config, nil, []string{})
+	}
+
+	type config struct {
+		BaseGroup
+		A         int
+		Log       *testConfigWriteLog
+		Upstreams []testGroupUpstream
+		Mirrors   []testGroupUpstream
+		Databases map[string]*testGroupDatabase
+	}
+
+	dir, err := ioutil.TempDir("", "cvc")
+	t.NoError(err)
+	defer os.RemoveAll(dir)
+
+	a := &config{Log: &testConfigWriteLog{}}
+	manager := newManager(a)
+	t.NoError(manager.SetViperConfig("yml", []byte(`
+naru:
+  a: 3
+  log:
+    level: error
+  upstreams:
+    - host: a
+      port: 8080
+    - host: b
+  mirrors:
+    - host: c
+      timeout: 3s
+  databases:
+    primary:
+      dsn: postgres://primary
+`)))
+	_, err = manager.Merge()
+	t.NoError(err)
+
+	f := filepath.Join(dir, "config")
+	t.NoError(manager.WriteConfigFile(f, "hcl"))
+
+	written, err := ioutil.ReadFile(f)
+	t.NoError(err)
+	t.Equal("hcl", sniffFormat(written))
+	t.Contains(string(written), "naru {")
+
+	b := &config{Log: &testConfigWriteLog{}}
+	manager = newManager(b)
+	t.NoError(manager.SetViperConfigFile(f))
+	_, err = manager.Merge()
+	t.NoError(err)
+	t.Equal(a, b)
+}
+
+type testFormatRoundTripConfig struct {
+	BaseGroup
+	Int       int
+	Uint      uint16
+	Float     float64
+	Bool      bool
+	String    string
+	Timeout   time.Duration
+	Port      *int
+	Log       *testConfigWriteLog
+	Server    testGroupServerConfig `group:"true"`
+	Upstreams []testGroupUpstream
+	Databases map[string]*testGroupDatabase
+	Storage   testUnionStorage `union:"true" default:"fs"`
+	Any       interface{}
+}
+
+func (t *testFormat) TestWriteRoundTrip() {
+	newManager := func(config interface{}) *Manager {
+		manager := newTestManager(t.T(), config, nil, nil)
+		t.NoError(manager.RegisterVariant("storage", "s3", &testUnionS3{}))
+		t.NoError(manager.RegisterVariant("storage", "fs", &testUnionFS{}))
+		executeTestManager(t.T(), manager)
+
+		return manager
+	}
+
+	dir, err := ioutil.TempDir("", "cvc")
+	t.NoError(err)
+	defer os.RemoveAll(dir)
+
+	a := &testFormatRoundTripConfig{Log: &testConfigWriteLog{}}
+	manager := newManager(a)
+	t.NoError(manager.SetViperConfig("yml", []byte(`
+naru:
+  int: -3
+  uint: 3
+  float: 1.5
+  bool: true
+  string: naru
+  timeout: 3s
+  port: 8080
+  log:
+    level: error
+  server:
+    host: localhost
+    port: 8080
+  upstreams:
+    - host: a
+      port: 8080
+    - host: b
+      timeout: 1s
+  databases:
+    primary:
+      dsn: postgres://primary
+  storage:
+    type: s3
+    s3:
+      bucket: naru
+  any:
+    a: naru
+    b: [c]
+`)))
+	_, err = manager.Merge()
+	t.NoError(err)
+
+	t.Equal(map[string]interface{}{"a": "naru", "b": []interface{}{"c"}}, a.Any)
+
+	for _, format := range []string{"yaml", "json", "toml", "hcl"} {
+		for _, write := range []func(string, string) error{manager.WriteConfigFile, manager.WriteChangedConfigFile} {
+			f := filepath.Join(dir, "config."+format)
+			t.NoError(write(f, format), format)
+
+			b := &testFormatRoundTripConfig{Log: &testConfigWriteLog{}}
+			loaded := newManager(b)
+			t.NoError(loaded.SetViperConfigFile(f), format)
+			_, err = loaded.Merge()
+			t.NoError(err, format)
+			t.Equal(a, b, format)
+		}
+	}
+
+	// env has only the string values
+	f := filepath.Join(dir, "config.env")
+	t.Error(manager.WriteConfigFile(f, "env"))
+
+	t.NoError(manager.SetValue("any", "naru"))
+	t.NoError(manager.WriteConfigFile(f, "env"))
+
+	b := &testFormatRoundTripConfig{Log: &testConfigWriteLog{}}
+	loaded := newManager(b)
+	t.NoError(loaded.SetViperConfigFile(f))
+	_, err = loaded.Merge()
+	t.NoError(err)
+	t.Equal(a, b)
+}
+
+func TestFormat(t *testing.T) {
+	suite.Run(t, new(testFormat))
+}
@@ -0,0 +1,313 @@
+package cvc
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// testGroupServerConfig is the config struct of the other library, which does
+// not embed BaseGroup.
+type testGroupServerConfig struct {
+	Host string
+	Port int
+}
+
+func (s *testGroupServerConfig) Validate() error {
+	if s.Port == 80 {
+		return errors.New("80 is not allowed")
+	}
+
+	return nil
+}
+
+type testGroupCommon struct {
+	Home  string
+	Debug bool
+}
+
+type testGroupConfig struct {
+	BaseGroup
+	testGroupCommon
+
+	Server  testGroupServerConfig  `group:"true"`
+	Backup  *testGroupServerConfig `group:"true"`
+	Address testGroupServerConfig
+}
+
+func (c *testGroupConfig) ParseHome(i string) (string, error) {
+	return "/home/" + i, nil
+}
+
+type testGroup struct {
+	suite.Suite
+}
+
+var testGroupEnvs = map[string]string{"NARU_BACKUP_HOST": "backup"}
+
+func (t *testGroup) TestKeys() {
+	manager := newTestManager(t.T(), &testGroupConfig{}, testGroupEnvs, []string{})
+
+	var keys []string
+	for k := range manager.Map() {
+		keys = append(keys, k)
+	}
+
+	t.ElementsMatch([]string{
+		"home",
+		"debug",
+		"server",
+		"server.host",
+		"server.port",
+		"backup",
+		"backup.host",
+		"backup.port",
+		"address",
+	}, keys)
+	t.True(manager.Map()["server"].IsGroup)
+	t.False(manager.Map()["address"].IsGroup)
+}
+
+func (t *testGroup) TestMerge() {
+	config := &testGroupConfig{}
+	manager := newTestManager(t.T(), config, testGroupEnvs, []string{"--home", "naru", "--debug", "--server-port", "8080"})
+
+	manager.SetViperConfig("yml", []byte(`
+naru:
+  server:
+    host: localhost
+`))
+
+	_, err := manager.Merge()
+	t.NoError(err)
+
+	t.Equal("/home/naru", config.Home)
+	t.True(config.Debug)
+	t.Equal("localhost", config.Server.Host)
+	t.Equal(8080, config.Server.Port)
+	t.Equal("backup", config.Backup.Host)
+}
+
+func (t *testGroup) TestValidate() {
+	manager := newTestManager(t.T(), &testGroupConfig{}, testGroupEnvs, []string{"--server-port", "80"})
+
+	key, err := manager.Merge()
+	t.Equal("server", key)
+	t.True(errors.Is(err, ErrorValidation))
+}
+
+type testGroupUpstream struct {
+	BaseGroup
+	Host    string
+	Port    int `default:"80"`
+	Timeout time.Duration
+}
+
+func (u *testGroupUpstream) Validate() error {
+	if len(u.Host) < 1 {
+		return errors.New("empty host")
+	}
+
+	return nil
+}
+
+type testGroupListConfig struct {
+	BaseGroup
+	Upstreams []testGroupUpstream
+	Backups   []*testGroupServerConfig `group:"true"`
+}
+
+func (t *testGroup) TestListKeys() {
+	config := &testGroupListConfig{Upstreams: []testGroupUpstream{{Host: "a"}}}
+	manager := newTestManager(t.T(), config, nil, []string{})
+
+	t.True(manager.Map()["upstreams"].IsList)
+	t.True(manager.Map()["upstreams.0"].IsGroup)
+	t.Contains(manager.Map(), "upstreams.0.host")
+	t.Equal(80, config.Upstreams[0].Port)
+	t.Nil(manager.FlagSet().Lookup("upstreams-0-host"))
+	t.Contains(manager.Envs(), "NARU_UPSTREAMS_0_HOST")
+}
+
+func (t *testGroup) TestListFromConfig() {
+	config := &testGroupListConfig{}
+	manager := newTestManager(t.T(), config, nil, []string{})
+
+	manager.SetViperConfig("yml", []byte(`
+naru:
+  upstreams:
+    - host: a
+      port: 8080
+      timeout: 3s
+    - host: b
+  backups:
+    - host: c
+`))
+
+	_, err := manager.Merge()
+	t.NoError(err)
+
+	t.Equal([]testGroupUpstream{
+		{Host: "a", Port: 8080, Timeout: time.Second * 3},
+		{Host: "b", Port: 80},
+	}, config.Upstreams)
+	t.Equal(1, len(config.Backups))
+	t.Equal("c", config.Backups[0].Host)
+
+	v, found := manager.Snapshot().Get("upstreams.1.host")
+	t.True(found)
+	t.Equal("b", v)
+
+	t.Contains(manager.configMap(false)["naru"], "upstreams")
+}
+
+func (t *testGroup) TestListFromEnv() {
+	config := &testGroupListConfig{Upstreams: []testGroupUpstream{{Host: "a"}}}
+	manager := newTestManager(t.T(), config, map[string]string{
+		"NARU_UPSTREAMS_0_HOST": "z",
+		"NARU_UPSTREAMS_1_HOST": "b",
+		"NARU_UPSTREAMS_2_HOST": "c",
+		"NARU_UPSTREAMS_4_HOST": "e",
+		"NARU_BACKUPS_0_HOST":   "d",
+	}, []string{})
+
+	_, err := manager.Merge()
+	t.NoError(err)
+
+	t.Equal([]testGroupUpstream{
+		{Host: "z", Port: 80},
+		{Host: "b", Port: 80},
+		{Host: "c", Port: 80},
+	}, config.Upstreams)
+	t.Equal(1, len(config.Backups))
+	t.Equal("d", config.Backups[0].Host)
+}
+
+func (t *testGroup) TestListValidate() {
+	manager := newTestManager(t.T(), &testGroupListConfig{}, nil, []string{})
+	manager.SetViperConfig("yml", []byte(`
+naru:
+  upstreams:
+    - host: a
+    - port: 8080
+`))
+
+	key, err := manager.Merge()
+	t.Equal("upstreams.1", key)
+	t.True(errors.Is(err, ErrorValidation))
+}
+
+func (t *testGroup) TestListUnknownKey() {
+	manager := newTestManager(t.T(), &testGroupListConfig{}, nil, []string{})
+	manager.SetViperConfig("yml", []byte(`
+naru:
+  upstreams:
+    - hostname: a
+`))
+
+	key, err := manager.Merge()
+	t.Equal("naru.upstreams.0.hostname", key)
+	t.True(errors.Is(err, ErrorUnknownKey))
+}
+
+type testGroupDatabase struct {
+	BaseGroup
+	DSN     string
+	Pool    int `default:"10"`
+	Timeout time.Duration
+}
+
+func (d *testGroupDatabase) Validate() error {
+	if len(d.DSN) < 1 {
+		return errors.New("empty dsn")
+	}
+
+	return nil
+}
+
+type testGroupMapConfig struct {
+	BaseGroup
+	Databases map[string]*testGroupDatabase
+}
+
+func (t *testGroup) TestMapFromConfig() {
+	config := &testGroupMapConfig{}
+	manager := newTestManager(t.T(), config, nil, []string{})
+	t.NoError(manager.SetMapTemplate("databases", &testGroupDatabase{Timeout: time.Second}))
+
+	manager.SetViperConfig("yml", []byte(`
+naru:
+  databases:
+    primary:
+      dsn: postgres://primary
+    replica:
+      dsn: postgres://replica
+      pool: 3
+`))
+
+	_, err := manager.Merge()
+	t.NoError(err)
+
+	t.Equal(map[string]*testGroupDatabase{
+		"primary": {DSN: "postgres://primary", Pool: 10, Timeout: time.Second},
+		"replica": {DSN: "postgres://replica", Pool: 3, Timeout: time.Second},
+	}, config.Databases)
+	t.True(manager.Map()["databases"].IsMap)
+	t.Contains(manager.Map(), "databases.replica.pool")
+
+	v, found := manager.Snapshot().Get("databases.primary.dsn")
+	t.True(found)
+	t.Equal("postgres://primary", v)
+}
+
+func (t *testGroup) TestMapFromEnv() {
+	envs := map[string]string{
+		"NARU_DATABASES_PRIMARY_DSN":      "postgres://primary",
+		"NARU_DATABASES_READ_REPLICA_DSN": "postgres://replica",
+	}
+
+	config := &testGroupMapConfig{}
+	manager := newTestManager(t.T(), config, envs, []string{})
+	manager.SetEnvironFunc(func() []string {
+		var l []string
+		for k, v := range envs {
+			l = append(l, k+"="+v)
+		}
+		return append(l, "NARU_DATABASES_DSN=unknown")
+	})
+
+	_, err := manager.Merge()
+	t.NoError(err)
+
+	t.Equal(map[string]*testGroupDatabase{
+		"primary":      {DSN: "postgres://primary", Pool: 10},
+		"read_replica": {DSN: "postgres://replica", Pool: 10},
+	}, config.Databases)
+}
+
+func (t *testGroup) TestMapValidate() {
+	manager := newTestManager(t.T(), &testGroupMapConfig{}, nil, []string{})
+	manager.SetViperConfig("yml", []byte(`
+naru:
+  databases:
+    primary:
+      pool: 3
+`))
+
+	key, err := manager.Merge()
+	t.Equal("databases.primary", key)
+	t.True(errors.Is(err, ErrorValidation))
+}
+
+func (t *testGroup) TestMapTemplate() {
+	manager := newTestManager(t.T(), &testGroupMapConfig{}, nil, []string{})
+
+	t.True(errors.Is(manager.SetMapTemplate("unknown", &testGroupDatabase{}), ErrorKeyNotFound))
+	t.True(errors.Is(manager.SetMapTemplate("databases", testGroupDatabase{}), ErrorNotAssignable))
+}
+
+func TestGroup(t *testing.T) {
+	suite.Run(t, new(testGroup))
+}
@@ -0,0 +1,68 @@
+package cvc
+
+import (
+	"fmt"
+)
+
+// The stages of Merge. The groups can have the hook methods by the stage
+// name, like `AfterEnv() error`, and the parent group can have the hook for
+// the field, like `AfterEnvLog() error`; the hooks of the children are called
+// before the parent.
+const (
+	HookBeforeMerge     = "BeforeMerge"
+	HookAfterEnv        = "AfterEnv"
+	HookAfterConfigFile = "AfterConfigFile"
+	HookAfterFlags      = "AfterFlags"
+	HookFinalize        = "Finalize"
+)
+
+var hookStages = []string{
+	HookBeforeMerge,
+	HookAfterEnv,
+	HookAfterConfigFile,
+	HookAfterFlags,
+	HookFinalize,
+}
+
+// AddHook adds the callback of the stage; the callbacks are called after the
+// hook methods of the config in the order of being added.
+func (m *Manager) AddHook(stage string, fn func(*Manager) error) error {
+	m.Lock()
+	defer m.Unlock()
+
+	var found bool
+	for _, s := range hookStages {
+		if s == stage {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("unknown hook stage: '%s'", stage)
+	}
+
+	if m.hooks == nil {
+		m.hooks = map[string][]func(*Manager) error{}
+	}
+	m.hooks[stage] = append(m.hooks[stage], fn)
+
+	return nil
+}
+
+func (m *Manager) runHook(stage string) (string, error) {
+	if t, err := m.root.Hook(stage); err != nil {
+		return t, err
+	}
+
+	m.RLock()
+	hooks := m.hooks[stage]
+	m.RUnlock()
+
+	for _, fn := range hooks {
+		if err := fn(m); err != nil {
+			return "", err
+		}
+	}
+
+	return "", nil
+}
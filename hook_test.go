@@ -0,0 +1,130 @@
+package cvc
+
+import (
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type testHookLogConfig struct {
+	BaseGroup
+
+	File string
+
+	called *[]string
+}
+
+func (l *testHookLogConfig) AfterFlags() error {
+	*l.called = append(*l.called, "log.AfterFlags")
+	return nil
+}
+
+type testHookConfig struct {
+	BaseGroup
+
+	Home string
+	Data string
+	Log  *testHookLogConfig
+
+	called []string
+	err    map[string]error
+}
+
+func (c *testHookConfig) record(stage string) error {
+	c.called = append(c.called, stage)
+	return c.err[stage]
+}
+
+func (c *testHookConfig) BeforeMerge() error     { return c.record(HookBeforeMerge) }
+func (c *testHookConfig) AfterEnv() error        { return c.record(HookAfterEnv) }
+func (c *testHookConfig) AfterConfigFile() error { return c.record(HookAfterConfigFile) }
+func (c *testHookConfig) Finalize() error        { return c.record(HookFinalize) }
+
+func (c *testHookConfig) AfterFlags() error {
+	if len(c.Data) < 1 {
+		c.Data = filepath.Join(c.Home, "data")
+	}
+
+	return c.record(HookAfterFlags)
+}
+
+func (c *testHookConfig) FinalizeHome() error {
+	c.called = append(c.called, "FinalizeHome")
+	return nil
+}
+
+type testHook struct {
+	suite.Suite
+}
+
+func (t *testHook) newManager(args ...string) (*testHookConfig, *Manager) {
+	config := &testHookConfig{
+		Home: "/home/naru",
+		err:  map[string]error{},
+	}
+	config.Log = &testHookLogConfig{called: &config.called}
+
+	manager := newTestManager(t.T(), config, nil, args)
+
+	return config, manager
+}
+
+func (t *testHook) TestOrder() {
+	config, manager := t.newManager("--home", "/naru")
+
+	var called []string
+	for _, stage := range hookStages {
+		stage := stage
+		t.NoError(manager.AddHook(stage, func(*Manager) error {
+			called = append(called, stage)
+			return nil
+		}))
+	}
+
+	_, err := manager.Merge()
+	t.NoError(err)
+
+	t.Equal([]string{
+		HookBeforeMerge,
+		HookAfterEnv,
+		HookAfterConfigFile,
+		"log.AfterFlags",
+		HookAfterFlags,
+		"FinalizeHome",
+		HookFinalize,
+	}, config.called)
+	t.Equal(hookStages, called)
+
+	t.Equal("/naru/data", config.Data)
+}
+
+func (t *testHook) TestItemError() {
+	config, manager := t.newManager()
+	config.err[HookAfterConfigFile] = io.EOF
+
+	_, err := manager.Merge()
+	t.Equal(io.EOF, err)
+	t.Equal([]string{HookBeforeMerge, HookAfterEnv, HookAfterConfigFile}, config.called)
+}
+
+func (t *testHook) TestManagerError() {
+	config, manager := t.newManager()
+	t.NoError(manager.AddHook(HookAfterEnv, func(m *Manager) error {
+		return io.EOF
+	}))
+
+	_, err := manager.Merge()
+	t.Equal(io.EOF, err)
+	t.Equal([]string{HookBeforeMerge, HookAfterEnv}, config.called)
+}
+
+func (t *testHook) TestUnknownStage() {
+	_, manager := t.newManager()
+	t.Error(manager.AddHook("AfterAll", func(*Manager) error { return nil }))
+}
+
+func TestHook(t *testing.T) {
+	suite.Run(t, new(testHook))
+}
@@ -0,0 +1,16 @@
+// cvclint reports the hook methods of the cvc config groups, which have the
+// wrong signatures or do not match any field. It runs alone or with go vet.
+//
+//	cvclint ./...
+//	go vet -vettool=$(which cvclint) ./...
+package main
+
+import (
+	"golang.org/x/tools/go/analysis/singlechecker"
+
+	"github.com/spikeekips/cvc/hookcheck"
+)
+
+func main() {
+	singlechecker.Main(hookcheck.Analyzer)
+}
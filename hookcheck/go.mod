@@ -0,0 +1,15 @@
+module github.com/spikeekips/cvc/hookcheck
+
+go 1.24.0
+
+require (
+	github.com/stretchr/testify v1.3.0
+	golang.org/x/tools v0.40.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/mod v0.31.0 // indirect
+	golang.org/x/sync v0.19.0 // indirect
+)
@@ -0,0 +1,198 @@
+// Package hookcheck defines the analyzer, which reports the hook methods of
+// the cvc config groups, like `ParseXxx` and `ValidateXxx`, which have the
+// wrong signatures or do not match any field.
+package hookcheck
+
+import (
+	"go/types"
+	"reflect"
+
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+var Analyzer = &analysis.Analyzer{
+	Name: "hookcheck",
+	Doc:  "check the signatures of the hook methods of cvc config groups",
+	Run:  run,
+}
+
+var errorType = types.Universe.Lookup("error").Type()
+
+// hookMethodNames are the same with cvc.HookMethodNames; they are copied to
+// not depend on cvc, because the analyzer only needs go/types.
+var hookMethodNames = []string{
+	"ParseEnv",
+	"Parse",
+	"Validate",
+	"Merge",
+	"FlagValue",
+	"StringVar",
+	"BeforeMerge",
+	"AfterEnv",
+	"AfterConfigFile",
+	"AfterFlags",
+	"Finalize",
+}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	scope := pass.Pkg.Scope()
+	for _, name := range scope.Names() {
+		obj, ok := scope.Lookup(name).(*types.TypeName)
+		if !ok || obj.IsAlias() {
+			continue
+		}
+
+		named, ok := obj.Type().(*types.Named)
+		if !ok {
+			continue
+		}
+
+		st, ok := named.Underlying().(*types.Struct)
+		if !ok || !isGroup(types.NewPointer(named)) {
+			continue
+		}
+
+		fields := map[string]types.Type{}
+		collectFields(st, fields)
+
+		var names []string
+		for name := range fields {
+			names = append(names, name)
+		}
+
+		for i := 0; i < named.NumMethods(); i++ {
+			method := named.Method(i)
+
+			hook, field, matched, isHook := splitHookMethodName(method.Name(), names)
+			switch {
+			case matched:
+				var ft types.Type
+				if len(field) > 0 {
+					ft = fields[field]
+				}
+
+				if expected, valid := checkSignature(method.Type().(*types.Signature), hook, ft); !valid {
+					pass.Reportf(method.Pos(), "%s must be %s", method.Name(), expected)
+				}
+			case isHook:
+				pass.Reportf(method.Pos(), "%s does not match any field", method.Name())
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+// collectFields collects the items of the group; the fields of the embedded
+// struct are flattened.
+func collectFields(st *types.Struct, fields map[string]types.Type) {
+	for i := 0; i < st.NumFields(); i++ {
+		f := st.Field(i)
+		if f.Embedded() && f.Name() == "BaseGroup" {
+			continue
+		}
+
+		if f.Embedded() && reflect.StructTag(st.Tag(i)).Get("group") != "true" {
+			t := f.Type()
+			if p, ok := t.(*types.Pointer); ok {
+				t = p.Elem()
+			}
+			if est, ok := t.Underlying().(*types.Struct); ok {
+				collectFields(est, fields)
+				continue
+			}
+		}
+
+		if f.Exported() {
+			fields[f.Name()] = f.Type()
+		}
+	}
+}
+
+// checkSignature is the go/types version of the signature check of
+// Manager.Lint.
+func checkSignature(sig *types.Signature, hook string, ft types.Type) (string, bool) {
+	params, results := sig.Params(), sig.Results()
+	returnsError := results.Len() > 0 && types.Identical(results.At(results.Len()-1).Type(), errorType)
+
+	switch hook {
+	case "Parse", "ParseEnv":
+		input := "input"
+		if hook == "ParseEnv" {
+			input = "string"
+		}
+		output := "T"
+		if ft != nil {
+			output = types.TypeString(ft, types.RelativeTo(nil))
+		}
+		expected := "func(" + input + ") (" + output + ", error)"
+
+		valid := params.Len() == 1 && results.Len() == 2 && returnsError
+		if valid && hook == "ParseEnv" {
+			b, ok := params.At(0).Type().Underlying().(*types.Basic)
+			valid = ok && b.Kind() == types.String
+		}
+		if valid && ft != nil {
+			// the hook of the optional field, `*T`, may return T
+			valid = types.AssignableTo(results.At(0).Type(), ft)
+			if p, ok := ft.(*types.Pointer); ok && !valid {
+				_, isStruct := p.Elem().Underlying().(*types.Struct)
+				valid = !isStruct && types.AssignableTo(results.At(0).Type(), p.Elem())
+			}
+		}
+
+		return expected, valid
+	case "Validate":
+		valid := results.Len() == 1 && returnsError &&
+			(params.Len() == 0 || (params.Len() == 1 && isContext(params.At(0).Type())))
+
+		return "func() error or func(context.Context) error", valid
+	case "Merge", "BeforeMerge", "AfterEnv", "AfterConfigFile", "AfterFlags", "Finalize":
+		return "func() error", params.Len() == 0 && results.Len() == 1 && returnsError
+	default:
+		return "", true
+	}
+}
+
+// splitHookMethodName is the same with cvc.SplitHookMethodName.
+func splitHookMethodName(name string, fields []string) (hook, field string, matched, isHook bool) {
+	for _, h := range hookMethodNames {
+		if name == h {
+			return h, "", true, true
+		}
+		if !strings.HasPrefix(name, h) {
+			continue
+		}
+
+		rest := name[len(h):]
+		for _, f := range fields {
+			if rest == f {
+				return h, f, true, true
+			}
+		}
+
+		if rest[:1] == strings.ToUpper(rest[:1]) {
+			isHook = true
+		}
+	}
+
+	return "", "", false, isHook
+}
+
+func isGroup(t types.Type) bool {
+	obj, _, _ := types.LookupFieldOrMethod(t, false, nil, "ThisIsGroup")
+	_, ok := obj.(*types.Func)
+
+	return ok
+}
+
+func isContext(t types.Type) bool {
+	named, ok := t.(*types.Named)
+	if !ok || named.Obj().Pkg() == nil {
+		return false
+	}
+
+	return named.Obj().Pkg().Path() == "context" && named.Obj().Name() == "Context"
+}
@@ -0,0 +1,20 @@
+package hookcheck
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+type testHookcheck struct {
+	suite.Suite
+}
+
+func (t *testHookcheck) TestAnalyzer() {
+	analysistest.Run(t.T(), analysistest.TestData(), Analyzer, "a")
+}
+
+func TestHookcheck(t *testing.T) {
+	suite.Run(t, new(testHookcheck))
+}
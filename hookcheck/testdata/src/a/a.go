@@ -0,0 +1,75 @@
+package a
+
+import "context"
+
+type BaseGroup struct{}
+
+func (b *BaseGroup) ThisIsGroup() {}
+
+type LogLevel string
+
+type LogConfig struct {
+	BaseGroup
+
+	File  string
+	Level LogLevel
+}
+
+func (l *LogConfig) ParseLevel(input string) LogLevel { // want `ParseLevel must be func\(input\) \(a.LogLevel, error\)`
+	return LogLevel(input)
+}
+
+func (l *LogConfig) ParseEnvFile(input int) (string, error) { // want `ParseEnvFile must be func\(string\) \(string, error\)`
+	return "", nil
+}
+
+func (l *LogConfig) ValidateFile(ctx context.Context) error {
+	return nil
+}
+
+func (l *LogConfig) ValidateLevle() error { // want `ValidateLevle does not match any field`
+	return nil
+}
+
+func (l *LogConfig) MergeFile(i int) error { // want `MergeFile must be func\(\) error`
+	return nil
+}
+
+func (l *LogConfig) Validate(ctx context.Context) error {
+	return nil
+}
+
+type NotGroup struct {
+	Level LogLevel
+}
+
+func (n *NotGroup) ParseLevle() {}
+
+type Common struct {
+	Home string
+}
+
+type Config struct {
+	BaseGroup
+	Common
+
+	Port    *int
+	Timeout *int
+	Log     *LogConfig
+}
+
+func (c *Config) ParsePort(input string) (int, error) {
+	return 0, nil
+}
+
+func (c *Config) ParseTimeout(input string) (string, error) { // want `ParseTimeout must be func\(input\) \(\*int, error\)`
+	return "", nil
+}
+
+func (c *Config) ValidateHome() error {
+	return nil
+}
+
+func (c *Config) ValidateHoem() error { // want `ValidateHoem does not match any field`
+	return nil
+}
@@ -6,21 +6,20 @@ import (
 	logging "github.com/inconshreveable/log15"
 )
 
-var log logging.Logger = logging.New("module", "cvc")
+// log is the package logger, which is used by Manager without Logger; it
+// discards the logs until SetLogging is called or `CVC_VERBOSE=1` is set.
+var log Logger = NopLogger{}
+var log15Log logging.Logger = logging.New("module", "cvc")
 var verbose bool
 
 func init() {
-	var logLevel logging.Lvl = logging.LvlError
-	var logFormat logging.Format = logging.TerminalFormat()
-
 	if verboseEnv, found := os.LookupEnv("CVC_VERBOSE"); found && verboseEnv == "1" {
 		verbose = true
-		logLevel = logging.LvlDebug
+		SetLogging(logging.LvlDebug, logging.StreamHandler(os.Stdout, logging.TerminalFormat()))
 	}
-
-	SetLogging(logLevel, logging.StreamHandler(os.Stdout, logFormat))
 }
 
 func SetLogging(level logging.Lvl, handler logging.Handler) {
-	log.SetHandler(logging.LvlFilterHandler(level, handler))
+	log15Log.SetHandler(logging.LvlFilterHandler(level, handler))
+	log = NewLog15Logger(log15Log)
 }
@@ -1,9 +1,13 @@
 package cvc
 
 import (
+	"io/ioutil"
 	"os"
 
 	logging "github.com/inconshreveable/log15"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
 )
 
 func init() {
@@ -13,3 +17,41 @@ func init() {
 		logging.StreamHandler(os.Stdout, logging.TerminalFormat()),
 	)
 }
+
+// newTestManager makes the manager of the `naru` command for the config. The
+// envs are used instead of the envs of process, and the command is executed
+// with args; with nil args, it is not executed, so the manager can be set up,
+// like RegisterVariant, before executeTestManager.
+func newTestManager(
+	t require.TestingT, config interface{}, envs map[string]string, args []string, options ...Option,
+) *Manager {
+	cmd := &cobra.Command{
+		Use:   "naru",
+		Short: "naru",
+	}
+	cmd.SetOutput(ioutil.Discard)
+
+	manager := NewManager("", config, cmd, viper.New(), options...)
+	manager.SetEnvLookupFunc(func(s string) (string, bool) {
+		v, found := envs[s]
+		return v, found
+	})
+	manager.SetEnvironFunc(func() []string {
+		l := make([]string, 0, len(envs))
+		for k, v := range envs {
+			l = append(l, k+"="+v)
+		}
+		return l
+	})
+
+	if args != nil {
+		executeTestManager(t, manager, args...)
+	}
+
+	return manager
+}
+
+func executeTestManager(t require.TestingT, manager *Manager, args ...string) {
+	manager.Cobra().SetArgs(append([]string{}, args...))
+	require.NoError(t, manager.Cobra().Execute())
+}
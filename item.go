@@ -1,12 +1,13 @@
 package cvc
 
 import (
+	"context"
 	"fmt"
 	"reflect"
 	"regexp"
 	"strings"
-
-	logging "github.com/inconshreveable/log15"
+	"sync"
+	"time"
 )
 
 var (
@@ -14,6 +15,9 @@ var (
 	regexpEnvName *regexp.Regexp = regexp.MustCompile("(?i)^[a-z_][a-z0-9_]*$")
 )
 
+// Group is the struct, which has the nested items. The struct, which embeds
+// BaseGroup, is also treated as Group even if it overrides Validate with
+// `Validate(context.Context) error`.
 type Group interface {
 	ThisIsGroup()
 	Validate() error
@@ -40,7 +44,30 @@ type Item struct {
 	Tag       reflect.StructTag
 	Input     interface{}
 	IsGroup   bool
+	IsList    bool
+	IsMap     bool
+	IsUnion   bool
 	ViperName string
+	Default   interface{}
+
+	aliasInputs map[string]interface{}
+	naming      NamingStrategy
+	logger      func() Logger
+
+	// discriminator is the item, which selects the variant of union
+	discriminator *Item
+	// variants is the registered variants of the unions; it is set to root
+	variants variantTypes
+
+	// cachedPrefixes, fullName and flagType are set by parseConfig
+	cachedPrefixes []string
+	fullName       string
+	flagType       string
+
+	// envName is cached by Manager with the prefix of env names
+	envName   string
+	envPrefix string
+	envCached bool
 }
 
 func (c Item) String() string {
@@ -60,7 +87,11 @@ func (c Item) String() string {
 }
 
 func (c *Item) prefixes() []string {
-	var names []string
+	if c.cachedPrefixes != nil {
+		return c.cachedPrefixes[:len(c.cachedPrefixes):len(c.cachedPrefixes)]
+	}
+
+	names := []string{}
 
 	var group *Item = c.Group
 	for {
@@ -78,6 +109,52 @@ func (c *Item) prefixes() []string {
 	return names
 }
 
+// Naming returns the NamingStrategy of the item; the items in the same tree
+// share the NamingStrategy of the root.
+func (c *Item) Naming() NamingStrategy {
+	for i := c; i != nil; i = i.Group {
+		if i.naming != nil {
+			return i.naming
+		}
+	}
+
+	return DefaultNaming{}
+}
+
+// inside checks whether the item is the descendant of the group.
+func (c *Item) inside(group *Item) bool {
+	for i := c.Group; i != nil; i = i.Group {
+		if i == group {
+			return true
+		}
+	}
+
+	return false
+}
+
+// inCollection checks whether the item is the element of the list or map of
+// groups, or in the element.
+func (c *Item) inCollection() bool {
+	for i := c.Group; i != nil; i = i.Group {
+		if i.IsList || i.IsMap {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Logger returns the Logger of the Manager, which has the item.
+func (c *Item) Logger() Logger {
+	for i := c; i != nil; i = i.Group {
+		if i.logger != nil {
+			return i.logger()
+		}
+	}
+
+	return log
+}
+
 func (c *Item) EnableFlag() bool {
 	i := c
 	for {
@@ -94,17 +171,11 @@ func (c *Item) EnableFlag() bool {
 }
 
 func (c *Item) FlagName() string {
-	tag := c.Tag.Get("flag")
-	switch {
-	case tag == "-":
+	if c.Tag.Get("flag") == "-" {
 		return ""
-	case len(tag) > 0:
-		return strings.Replace(c.name(tag), ".", "-", -1)
-	default:
-		return strings.Replace(c.FullName(), ".", "-", -1)
 	}
 
-	return ""
+	return c.Naming().FlagName(append(c.prefixes(), c.Name()))
 }
 
 func (c *Item) EnvName(prefix string) string {
@@ -119,10 +190,19 @@ func (c *Item) EnvName(prefix string) string {
 		t = c.Name()
 	}
 
+	s := c.Naming().EnvName(prefix, append(c.prefixes(), t))
+	if len(s) < 1 {
+		c.Logger().Error("invalid env name found", "item", c.FullName())
+	}
+
+	return s
+}
+
+func envName(prefix string, names []string) string {
 	s := strings.Replace(
 		strings.ToUpper(
 			NormalizeVar(
-				strings.Join(append(c.prefixes(), t), "_"),
+				strings.Join(names, "_"),
 				"_",
 			),
 		),
@@ -135,18 +215,43 @@ func (c *Item) EnvName(prefix string) string {
 	}
 
 	if !regexpEnvName.MatchString(s) {
-		log.Error("invalid env name found", "name", s)
 		return ""
 	}
 
 	return s
 }
 
+// Aliases returns the old keys of the item from the `alias` tag. The alias,
+// which has '.', is the key from the root, otherwise it is the name in the
+// same group.
+func (c *Item) Aliases() []string {
+	var aliases []string
+	for _, names := range c.aliasNames() {
+		aliases = append(aliases, c.Naming().ConfigKey(names))
+	}
+
+	return aliases
+}
+
+func (c *Item) aliasNames() [][]string {
+	var names [][]string
+	for _, a := range strings.Split(c.Tag.Get("alias"), ",") {
+		a = strings.TrimSpace(a)
+		switch {
+		case len(a) < 1:
+			continue
+		case strings.Contains(a, "."):
+			names = append(names, strings.Split(a, "."))
+		default:
+			names = append(names, append(c.prefixes(), a))
+		}
+	}
+
+	return names
+}
+
 func (c *Item) name(n string) string {
-	return NormalizeVar(
-		strings.Join(append(c.prefixes(), n), "."),
-		".",
-	)
+	return c.Naming().ConfigKey(append(c.prefixes(), n))
 }
 
 func (c *Item) Name() string {
@@ -161,31 +266,213 @@ func (c *Item) Name() string {
 }
 
 func (c *Item) FullName() string {
+	if len(c.fullName) > 0 {
+		return c.fullName
+	}
+
 	return c.name(c.Name())
 }
 
+func (c *Item) FlagType() string {
+	if len(c.flagType) > 0 {
+		return c.flagType
+	}
+
+	fns := GetFuncFromItem(c, "Parse", 1, 2)
+	t := getConfigTypeByFuncs(fns...)
+
+	if len(t) < 1 {
+		t = getConfigTypeByValue(c.elemValue())
+	}
+
+	return t
+}
+
+func (c *Item) flagDefaultValue(t string) reflect.Value {
+	if d, err := GetFlagValue(c); err == nil {
+		return d
+	} else if t != "StringVar" {
+		return c.elemValue()
+	}
+
+	v := c.elemValue()
+	method, found := GetMethodByName(v.Interface(), "StringVar", 0, 1)
+	if !found {
+		return reflect.ValueOf(fmt.Sprintf("%v", v.Interface()))
+	}
+
+	vs := method.Call()
+	if len(vs) < 1 {
+		return v
+	}
+
+	return vs[0]
+}
+
+// isOptional checks whether the item is the pointer of scalar value, like
+// `*int`; the optional item stays nil unless it is set.
+func (c *Item) isOptional() bool {
+	return !c.IsGroup && c.Value.Kind() == reflect.Ptr && c.Value.Type().Elem().Kind() != reflect.Struct
+}
+
+// elemValue returns the value of the optional item, or the zero value if it is
+// not set; the value of the other item is returned as it is.
+func (c *Item) elemValue() reflect.Value {
+	switch {
+	case !c.isOptional():
+		return c.Value
+	case c.Value.IsNil():
+		return reflect.Zero(c.Value.Type().Elem())
+	default:
+		return c.Value.Elem()
+	}
+}
+
+// jsonPath returns the path of the item in the json of config, like
+// `Log.Level`; the name is from the `json` tag.
+func jsonPath(c *Item) string {
+	var names []string
+	for i := c; i.Group != nil; i = i.Group {
+		name := i.FieldName
+		if n := strings.Split(i.Tag.Get("json"), ",")[0]; len(n) > 0 {
+			name = n
+		}
+
+		names = append([]string{name}, names...)
+	}
+
+	return strings.Join(names, ".")
+}
+
+// set sets the value; when the discriminator of union is set, the selected
+// variant is set to the union.
+func (c *Item) set(v reflect.Value) {
+	c.Value.Set(v)
+
+	if g := c.Group; g != nil && g.IsUnion && g.discriminator == c {
+		g.selectVariant()
+	}
+}
+
+// Changed checks whether the value is different from the default; the
+// optional item without default is changed when it is set, even to the zero
+// value.
+func (c *Item) Changed() bool {
+	if c.isOptional() {
+		switch {
+		case c.Value.IsNil():
+			return c.Default != nil
+		case c.Default == nil:
+			return true
+		}
+	}
+
+	return !reflect.DeepEqual(c.flagDefaultValue(c.FlagType()).Interface(), c.Default)
+}
+
+// configValue returns the value written to the config files; the value of
+// interface item is written as it is, not as the string of flag.
+func (c *Item) configValue() interface{} {
+	if c.Value.Kind() == reflect.Interface {
+		return c.Value.Interface()
+	}
+
+	v := c.flagDefaultValue(c.FlagType()).Interface()
+	if d, ok := v.(time.Duration); ok {
+		return d.String()
+	}
+
+	return v
+}
+
 func (c *Item) Validate() (string, error) {
-	for _, c := range c.Children {
-		if n, err := c.Validate(); err != nil {
-			return n, err
+	return c.ValidateContext(context.Background())
+}
+
+// ValidateContext validates the children groups concurrently and then the
+// other children and itself in order. When the multiple groups fail, the error
+// of the first group is returned.
+func (c *Item) ValidateContext(ctx context.Context) (string, error) {
+	type result struct {
+		name string
+		err  error
+	}
+
+	results := make([]result, len(c.Children))
+
+	var wg sync.WaitGroup
+	for i, child := range c.Children {
+		if !child.IsGroup || child.unselected() {
+			continue
 		}
+
+		wg.Add(1)
+		go func(i int, child *Item) {
+			defer wg.Done()
+
+			n, err := child.ValidateContext(ctx)
+			results[i] = result{name: n, err: err}
+		}(i, child)
 	}
 
-	if err := c.validate(); err != nil {
+	// the children get ctx to stop early; they are waited, so they do not
+	// touch the config after return.
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return c.FullName(), err
+	}
+
+	for i, child := range c.Children {
+		if child.unselected() {
+			continue
+		}
+
+		if !child.IsGroup {
+			if err := ctx.Err(); err != nil {
+				return child.FullName(), err
+			}
+			results[i].name, results[i].err = child.ValidateContext(ctx)
+		}
+
+		if results[i].err != nil {
+			return results[i].name, results[i].err
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return c.FullName(), err
+	}
+
+	if err := c.validate(ctx); err != nil {
 		return c.FullName(), err
 	}
 
 	return "", nil
 }
 
-func (c *Item) validate() error {
+func (c *Item) validate(ctx context.Context) error {
+	if c.IsUnion {
+		if err := c.validateUnion(); err != nil {
+			return err
+		}
+	}
+
 	if (c.Value.Kind() == reflect.Ptr && c.Value.Type().Elem().Kind() == reflect.Struct) && c.Value.IsNil() {
 		return nil
 	}
 
 	fns := GetFuncFromItem(c, "Validate", 0, 1)
+	if len(fns) < 1 {
+		for _, f := range GetFuncFromItem(c, "Validate", 1, 1) {
+			if f.In(0) == contextType {
+				fns = append(fns, f)
+			}
+		}
+	}
+
 	for _, f := range fns {
-		return CallValidateFunc(f)
+		return callValidateFunc(ctx, c.Logger(), f)
 	}
 
 	return nil
@@ -193,6 +480,9 @@ func (c *Item) validate() error {
 
 func (c *Item) Merge() (string, error) {
 	for _, c := range c.Children {
+		if c.unselected() {
+			continue
+		}
 		if n, err := c.Merge(); err != nil {
 			return n, err
 		}
@@ -212,43 +502,102 @@ func (c *Item) merge() error {
 
 	fns := GetFuncFromItem(c, "Merge", 0, 1)
 	for _, f := range fns {
-		return CallMergeFunc(f)
+		return returnedError(c.Logger(), "MergeXXX()", f.Call()[0])
 	}
 
 	return nil
 }
 
+func (c *Item) Hook(name string) (string, error) {
+	for _, c := range c.Children {
+		if c.unselected() {
+			continue
+		}
+		if n, err := c.Hook(name); err != nil {
+			return n, err
+		}
+	}
+
+	if err := c.hook(name); err != nil {
+		return c.FullName(), err
+	}
+
+	return "", nil
+}
+
+func (c *Item) hook(name string) error {
+	if (c.Value.Kind() == reflect.Ptr && c.Value.Type().Elem().Kind() == reflect.Struct) && c.Value.IsNil() {
+		return nil
+	}
+
+	fns := GetFuncFromItem(c, name, 0, 1)
+	for _, f := range fns {
+		return returnedError(c.Logger(), "hook", f.Call()[0])
+	}
+
+	return nil
+}
+
+// Parse parses the input by the `Parse` hook; without the hook, the input is
+// returned as it is.
 func (c *Item) Parse(i interface{}) (interface{}, error) {
+	if len(GetFuncFromItem(c, "Parse", 1, 2)) < 1 {
+		return i, nil
+	}
+
+	return c.parse(i)
+}
+
+// parse parses the input like Parse, but the input without the hook is
+// converted to the type of item, like "1s" to time.Duration, because the
+// config files, env and flags have their own types of values.
+func (c *Item) parse(i interface{}) (interface{}, error) {
 	fns := GetFuncFromItem(c, "Parse", 1, 2)
 	for _, f := range fns {
-		return CallParseFunc(f, i)
+		return c.optionalValue(callParseFunc(c.Logger(), f, i))
 	}
 
-	return i, nil
+	return convertValue(c.Value.Type(), i)
+}
+
+// optionalValue returns the pointer of the value, which is parsed by the hook
+// of the optional item.
+func (c *Item) optionalValue(v interface{}, err error) (interface{}, error) {
+	if err != nil || v == nil || !c.isOptional() {
+		return v, err
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Type() == c.Value.Type() || !rv.Type().AssignableTo(c.Value.Type().Elem()) {
+		return v, nil
+	}
+
+	p := reflect.New(c.Value.Type().Elem())
+	p.Elem().Set(rv)
+
+	return p.Interface(), nil
 }
 
 func (c *Item) ParseEnv(i string) (interface{}, error) {
-	log_ := log.New(logging.Ctx{"item": c.FullName(), "action": "parseEnv", "input": i})
+	log_ := newContextLogger(c.Logger(), "item", c.FullName(), "action", "parseEnv", "input", i)
 
 	fns := GetFuncFromItem(c, "ParseEnv", 1, 2)
 	for _, f := range fns {
-		return CallParseFunc(f, i)
+		return c.optionalValue(callParseFunc(c.Logger(), f, i))
 	}
 
 	fns = GetFuncFromItem(c, "Parse", 1, 2)
 	t := getConfigTypeByFuncs(fns...)
 
 	if len(t) < 1 {
-		t = getConfigTypeByValue(c.Value)
+		t = getConfigTypeByValue(c.elemValue())
 	}
 
 	switch t {
 	case "StringVar":
-		return c.Parse(i)
+		return c.parse(i)
 	default:
 		log_.Error("not supported type", "type", t)
 		return nil, fmt.Errorf("failed to parse env value")
 	}
-
-	return nil, nil
 }
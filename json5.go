@@ -0,0 +1,408 @@
+package cvc
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// parseJSON5 decodes JSON5, https://spec.json5.org, like json.Unmarshal into
+// interface{}; the numbers are float64, and the unquoted keys may have '-',
+// like `file-name`, for the config keys.
+func parseJSON5(b []byte) (interface{}, error) {
+	p := &json5Parser{s: string(b)}
+
+	v, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.skip(); err != nil {
+		return nil, err
+	}
+	if p.i < len(p.s) {
+		return nil, p.errorf("unexpected character %q", p.s[p.i])
+	}
+
+	return v, nil
+}
+
+type json5Parser struct {
+	s string
+	i int
+}
+
+func (p *json5Parser) errorf(format string, args ...interface{}) error {
+	line := strings.Count(p.s[:p.i], "\n") + 1
+
+	return fmt.Errorf("json5: line %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+// skip skips the white spaces and the comments.
+func (p *json5Parser) skip() error {
+	for p.i < len(p.s) {
+		r, size := utf8.DecodeRuneInString(p.s[p.i:])
+		switch {
+		case r == '\ufeff' || unicode.IsSpace(r):
+			p.i += size
+		case strings.HasPrefix(p.s[p.i:], "//"):
+			end := strings.IndexAny(p.s[p.i:], "\n\r\u2028\u2029")
+			if end < 0 {
+				p.i = len(p.s)
+			} else {
+				p.i += end
+			}
+		case strings.HasPrefix(p.s[p.i:], "/*"):
+			end := strings.Index(p.s[p.i+2:], "*/")
+			if end < 0 {
+				return p.errorf("unterminated comment")
+			}
+			p.i += end + 4
+		default:
+			return nil
+		}
+	}
+
+	return nil
+}
+
+func (p *json5Parser) value() (interface{}, error) {
+	if err := p.skip(); err != nil {
+		return nil, err
+	}
+	if p.i >= len(p.s) {
+		return nil, p.errorf("unexpected end of input")
+	}
+
+	switch c := p.s[p.i]; {
+	case c == '{':
+		return p.object()
+	case c == '[':
+		return p.array()
+	case c == '"' || c == '\'':
+		return p.str()
+	case strings.HasPrefix(p.s[p.i:], "null"):
+		p.i += 4
+		return nil, nil
+	case strings.HasPrefix(p.s[p.i:], "true"):
+		p.i += 4
+		return true, nil
+	case strings.HasPrefix(p.s[p.i:], "false"):
+		p.i += 5
+		return false, nil
+	default:
+		return p.number()
+	}
+}
+
+func (p *json5Parser) object() (interface{}, error) {
+	p.i++ // {
+
+	m := map[string]interface{}{}
+	for {
+		if err := p.skip(); err != nil {
+			return nil, err
+		}
+		if p.i >= len(p.s) {
+			return nil, p.errorf("unterminated object")
+		}
+		if p.s[p.i] == '}' {
+			p.i++
+			return m, nil
+		}
+
+		k, err := p.key()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := p.skip(); err != nil {
+			return nil, err
+		}
+		if p.i >= len(p.s) || p.s[p.i] != ':' {
+			return nil, p.errorf("missing ':' after key %q", k)
+		}
+		p.i++
+
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		m[k] = v
+
+		if err := p.next('}'); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *json5Parser) array() (interface{}, error) {
+	p.i++ // [
+
+	l := []interface{}{}
+	for {
+		if err := p.skip(); err != nil {
+			return nil, err
+		}
+		if p.i >= len(p.s) {
+			return nil, p.errorf("unterminated array")
+		}
+		if p.s[p.i] == ']' {
+			p.i++
+			return l, nil
+		}
+
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		l = append(l, v)
+
+		if err := p.next(']'); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// next consumes the comma between the members; the trailing comma is allowed.
+func (p *json5Parser) next(end byte) error {
+	if err := p.skip(); err != nil {
+		return err
+	}
+	if p.i >= len(p.s) {
+		return p.errorf("missing '%c'", end)
+	}
+
+	switch p.s[p.i] {
+	case ',':
+		p.i++
+		return nil
+	case end:
+		return nil
+	default:
+		return p.errorf("unexpected character %q", p.s[p.i])
+	}
+}
+
+func (p *json5Parser) key() (string, error) {
+	if c := p.s[p.i]; c == '"' || c == '\'' {
+		return p.str()
+	}
+
+	var k strings.Builder
+	for p.i < len(p.s) {
+		r, size := utf8.DecodeRuneInString(p.s[p.i:])
+		if r == '\\' {
+			if !strings.HasPrefix(p.s[p.i:], `\u`) {
+				return "", p.errorf("invalid escape in key")
+			}
+			p.i++
+			u, err := p.unicodeEscape()
+			if err != nil {
+				return "", err
+			}
+			k.WriteRune(u)
+			continue
+		}
+
+		isStart := r == '$' || r == '_' || unicode.IsLetter(r)
+		if !isStart && (k.Len() < 1 || !(r == '-' || unicode.IsDigit(r) || unicode.In(r, unicode.Mn, unicode.Mc, unicode.Pc))) {
+			break
+		}
+
+		k.WriteRune(r)
+		p.i += size
+	}
+
+	if k.Len() < 1 {
+		return "", p.errorf("invalid key")
+	}
+
+	return k.String(), nil
+}
+
+func (p *json5Parser) str() (string, error) {
+	quote := p.s[p.i]
+	p.i++
+
+	var w strings.Builder
+	for {
+		if p.i >= len(p.s) {
+			return "", p.errorf("unterminated string")
+		}
+
+		c := p.s[p.i]
+		switch {
+		case c == quote:
+			p.i++
+			return w.String(), nil
+		case c == '\n' || c == '\r':
+			return "", p.errorf("line break in string")
+		case c != '\\':
+			w.WriteByte(c)
+			p.i++
+			continue
+		}
+
+		p.i++ // backslash
+		if p.i >= len(p.s) {
+			return "", p.errorf("unterminated string")
+		}
+
+		r, size := utf8.DecodeRuneInString(p.s[p.i:])
+		p.i += size
+		switch r {
+		case 'b':
+			w.WriteByte('\b')
+		case 'f':
+			w.WriteByte('\f')
+		case 'n':
+			w.WriteByte('\n')
+		case 'r':
+			w.WriteByte('\r')
+		case 't':
+			w.WriteByte('\t')
+		case 'v':
+			w.WriteByte('\v')
+		case '0':
+			if p.i < len(p.s) && p.s[p.i] >= '0' && p.s[p.i] <= '9' {
+				return "", p.errorf("invalid escape '\\0%c'", p.s[p.i])
+			}
+			w.WriteByte(0)
+		case 'x':
+			if p.i+2 > len(p.s) {
+				return "", p.errorf("invalid escape '\\x'")
+			}
+			n, err := strconv.ParseUint(p.s[p.i:p.i+2], 16, 8)
+			if err != nil {
+				return "", p.errorf("invalid escape '\\x%s'", p.s[p.i:p.i+2])
+			}
+			w.WriteRune(rune(n))
+			p.i += 2
+		case 'u':
+			p.i--
+			u, err := p.unicodeEscape()
+			if err != nil {
+				return "", err
+			}
+			w.WriteRune(u)
+		case '\r':
+			// the line continuation of CRLF
+			if p.i < len(p.s) && p.s[p.i] == '\n' {
+				p.i++
+			}
+		case '\n', '\u2028', '\u2029':
+			// the line continuation
+		default:
+			if r >= '1' && r <= '9' {
+				return "", p.errorf("invalid escape '\\%c'", r)
+			}
+			w.WriteRune(r)
+		}
+	}
+}
+
+// unicodeEscape reads `uXXXX` after the backslash; the surrogate pair is
+// combined.
+func (p *json5Parser) unicodeEscape() (rune, error) {
+	read := func() (rune, error) {
+		if p.i+5 > len(p.s) || p.s[p.i] != 'u' {
+			return 0, p.errorf("invalid unicode escape")
+		}
+		n, err := strconv.ParseUint(p.s[p.i+1:p.i+5], 16, 16)
+		if err != nil {
+			return 0, p.errorf("invalid unicode escape '\\%s'", p.s[p.i:p.i+5])
+		}
+		p.i += 5
+		return rune(n), nil
+	}
+
+	r, err := read()
+	if err != nil {
+		return 0, err
+	}
+
+	if utf16.IsSurrogate(r) && strings.HasPrefix(p.s[p.i:], `\u`) {
+		i := p.i
+		p.i++
+		low, err := read()
+		if d := utf16.DecodeRune(r, low); err == nil && d != utf8.RuneError {
+			return d, nil
+		}
+		p.i = i
+	}
+
+	return r, nil
+}
+
+func (p *json5Parser) number() (interface{}, error) {
+	start := p.i
+
+	sign := 1.0
+	if c := p.s[p.i]; c == '+' || c == '-' {
+		if c == '-' {
+			sign = -1
+		}
+		p.i++
+	}
+
+	rest := p.s[p.i:]
+	switch {
+	case strings.HasPrefix(rest, "Infinity"):
+		p.i += len("Infinity")
+		return math.Inf(int(sign)), nil
+	case strings.HasPrefix(rest, "NaN"):
+		p.i += len("NaN")
+		return math.NaN(), nil
+	case strings.HasPrefix(rest, "0x") || strings.HasPrefix(rest, "0X"):
+		p.i += 2
+		j := p.i
+		for p.i < len(p.s) && strings.IndexByte("0123456789abcdefABCDEF", p.s[p.i]) >= 0 {
+			p.i++
+		}
+		n, err := strconv.ParseUint(p.s[j:p.i], 16, 64)
+		if err != nil {
+			return nil, p.errorf("invalid number %q", p.s[start:p.i])
+		}
+		return sign * float64(n), nil
+	}
+
+	j := p.i
+	digits := func() int {
+		k := p.i
+		for p.i < len(p.s) && p.s[p.i] >= '0' && p.s[p.i] <= '9' {
+			p.i++
+		}
+		return p.i - k
+	}
+
+	n := digits()
+	if p.i < len(p.s) && p.s[p.i] == '.' {
+		p.i++
+		n += digits()
+	}
+	if n < 1 {
+		p.i = start
+		return nil, p.errorf("invalid value")
+	}
+	if p.i < len(p.s) && (p.s[p.i] == 'e' || p.s[p.i] == 'E') {
+		p.i++
+		if p.i < len(p.s) && (p.s[p.i] == '+' || p.s[p.i] == '-') {
+			p.i++
+		}
+		if digits() < 1 {
+			return nil, p.errorf("invalid number %q", p.s[start:p.i])
+		}
+	}
+
+	f, err := strconv.ParseFloat(p.s[j:p.i], 64)
+	if err != nil {
+		return nil, p.errorf("invalid number %q", p.s[start:p.i])
+	}
+
+	return sign * f, nil
+}
@@ -0,0 +1,191 @@
+package cvc
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// HookMethodNames are the names of the hook methods; the group can have them
+// for the field, like `ParseLevel`. The longer name comes first, so
+// `ParseEnvLevel` is matched with `ParseEnv`.
+var HookMethodNames = []string{
+	"ParseEnv",
+	"Parse",
+	"Validate",
+	"Merge",
+	"FlagValue",
+	"StringVar",
+	HookBeforeMerge,
+	HookAfterEnv,
+	HookAfterConfigFile,
+	HookAfterFlags,
+	HookFinalize,
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// SplitHookMethodName splits the method name into the hook name and the field
+// name; field is empty for the hook of the item itself. isHook is true when
+// the name starts with the hook name.
+func SplitHookMethodName(name string, fields []string) (hook, field string, matched, isHook bool) {
+	for _, h := range HookMethodNames {
+		if name == h {
+			return h, "", true, true
+		}
+		if !strings.HasPrefix(name, h) {
+			continue
+		}
+
+		rest := name[len(h):]
+		for _, f := range fields {
+			if rest == f {
+				return h, f, true, true
+			}
+		}
+
+		if rest[:1] == strings.ToUpper(rest[:1]) {
+			isHook = true
+		}
+	}
+
+	return "", "", false, isHook
+}
+
+// Lint reports the hook methods, which are ignored by Manager, because of the
+// wrong signatures or the missing fields, like `ParseLevel(string) LogLevel`
+// and `ParseLevle(string) (LogLevel, error)`, the config keys, which are same
+// in lower case, the invalid `default` tags and the `validate-help` tags
+// without the Validate hook.
+func (m *Manager) Lint() []error {
+	errs := lintItem(m.root)
+
+	if _, err := m.conflictedKey(); err != nil {
+		errs = append(errs, err)
+	}
+
+	items := m.Map()
+	keys := make([]string, 0, len(items))
+	for k := range items {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		item := items[k]
+		if tag, found := item.Tag.Lookup("default"); found && !item.IsGroup {
+			if _, err := item.parse(tag); err != nil {
+				errs = append(errs, &ParseError{Key: k, Source: SourceDefault, Input: tag, Err: err})
+			}
+		}
+
+		if len(validateHelp(item)) > 0 && len(validateMethods(item)) < 1 {
+			errs = append(errs, fmt.Errorf("'%s' has the validate-help tag without the Validate hook", k))
+		}
+	}
+
+	return errs
+}
+
+func lintItem(item *Item) []error {
+	var errs []error
+
+	// the methods of the interface of union are not hooks
+	if item.IsUnion {
+		for _, c := range item.Children {
+			errs = append(errs, lintItem(c)...)
+		}
+
+		return errs
+	}
+
+	t := methodBody(item).Type()
+	if !item.IsGroup {
+		for i := 0; i < t.NumMethod(); i++ {
+			method := t.Method(i)
+
+			if err := lintHookMethod(t, method, method.Name, t); err != nil {
+				errs = append(errs, err)
+			}
+		}
+
+		return errs
+	}
+
+	var names []string
+	fields := map[string]reflect.Type{}
+	for _, c := range item.Children {
+		names = append(names, c.FieldName)
+		fields[c.FieldName] = c.Value.Type()
+
+		errs = append(errs, lintItem(c)...)
+	}
+
+	for i := 0; i < t.NumMethod(); i++ {
+		method := t.Method(i)
+
+		hook, field, matched, isHook := SplitHookMethodName(method.Name, names)
+		switch {
+		case matched && len(field) < 1:
+			if err := lintHookMethod(t, method, hook, nil); err != nil {
+				errs = append(errs, err)
+			}
+		case matched:
+			if err := lintHookMethod(t, method, hook, fields[field]); err != nil {
+				errs = append(errs, err)
+			}
+		case isHook:
+			errs = append(errs, fmt.Errorf("%s.%s does not match any field", t, method.Name))
+		}
+	}
+
+	return errs
+}
+
+// lintHookMethod checks the signature of hook method; ft is the type of the
+// item, which is parsed by the method.
+func lintHookMethod(t reflect.Type, method reflect.Method, hook string, ft reflect.Type) error {
+	mt := method.Type
+	returnsError := mt.NumOut() > 0 && mt.Out(mt.NumOut()-1) == errorType
+
+	var valid bool
+	var expected string
+	switch hook {
+	case "Parse", "ParseEnv":
+		input := "input"
+		if hook == "ParseEnv" {
+			input = "string"
+		}
+		output := "T"
+		if ft != nil {
+			output = ft.String()
+		}
+		expected = fmt.Sprintf("func(%s) (%s, error)", input, output)
+
+		valid = mt.NumIn() == 2 && mt.NumOut() == 2 && returnsError
+		if valid && hook == "ParseEnv" {
+			valid = mt.In(1).Kind() == reflect.String
+		}
+		if valid && ft != nil {
+			// the hook of the optional field, `*T`, may return T
+			valid = mt.Out(0).AssignableTo(ft) ||
+				(ft.Kind() == reflect.Ptr && ft.Elem().Kind() != reflect.Struct && mt.Out(0).AssignableTo(ft.Elem()))
+		}
+	case "Validate":
+		expected = "func() error or func(context.Context) error"
+		valid = mt.NumOut() == 1 && returnsError &&
+			(mt.NumIn() == 1 || (mt.NumIn() == 2 && mt.In(1) == contextType))
+	case "Merge", HookBeforeMerge, HookAfterEnv, HookAfterConfigFile, HookAfterFlags, HookFinalize:
+		expected = "func() error"
+		valid = mt.NumIn() == 1 && mt.NumOut() == 1 && returnsError
+	default:
+		return nil
+	}
+
+	if valid {
+		return nil
+	}
+
+	return fmt.Errorf("%s.%s must be %s", t, method.Name, expected)
+}
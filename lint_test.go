@@ -0,0 +1,139 @@
+package cvc
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type testLintLogConfig struct {
+	BaseGroup
+
+	File  string `validate-help:"writable"`
+	Level string `validate-help:"one of debug, error"`
+}
+
+func (l *testLintLogConfig) ValidateFile(ctx context.Context) error {
+	return nil
+}
+
+func (l *testLintLogConfig) ValidateLevle() error {
+	return nil
+}
+
+func (l *testLintLogConfig) MergeLevel(i int) error {
+	return nil
+}
+
+type testLintConfig struct {
+	BaseGroup
+
+	Port int
+	Log  *testLintLogConfig
+}
+
+func (c *testLintConfig) ParseEnvPort(i int) (int, error) {
+	return i, nil
+}
+
+func (c *testLintConfig) AfterFlagsLog() {}
+
+type testLint struct {
+	suite.Suite
+}
+
+func (t *testLint) newManager(config interface{}) *Manager {
+	return newTestManager(t.T(), config, nil, nil)
+}
+
+func (t *testLint) TestLint() {
+	manager := t.newManager(&testLintConfig{Log: &testLintLogConfig{}})
+
+	var errs []string
+	for _, err := range manager.Lint() {
+		errs = append(errs, err.Error())
+	}
+
+	t.Equal([]string{
+		"*cvc.testLintLogConfig.MergeLevel must be func() error",
+		"*cvc.testLintLogConfig.ValidateLevle does not match any field",
+		"*cvc.testLintConfig.AfterFlagsLog must be func() error",
+		"*cvc.testLintConfig.ParseEnvPort must be func(string) (int, error)",
+		"'log.level' has the validate-help tag without the Validate hook",
+	}, errs)
+}
+
+type testLintOptionalConfig struct {
+	BaseGroup
+
+	Port    *int
+	Timeout *int
+}
+
+func (c *testLintOptionalConfig) ParsePort(i string) (int, error) {
+	return strconv.Atoi(i)
+}
+
+func (c *testLintOptionalConfig) ParseTimeout(i string) (string, error) {
+	return i, nil
+}
+
+func (t *testLint) TestOptional() {
+	manager := t.newManager(&testLintOptionalConfig{})
+
+	var errs []string
+	for _, err := range manager.Lint() {
+		errs = append(errs, err.Error())
+	}
+
+	t.Equal([]string{
+		"*cvc.testLintOptionalConfig.ParseTimeout must be func(input) (*int, error)",
+	}, errs)
+}
+
+func (t *testLint) TestClean() {
+	manager := t.newManager(&testConfig{})
+	t.Empty(manager.Lint())
+}
+
+type testLintIgnoredConfig struct {
+	Level string
+}
+
+func (c *testLintIgnoredConfig) ParseLevel(i string) string {
+	return "parsed"
+}
+
+func (c *testLintIgnoredConfig) MergeLevel(i int) error {
+	return nil
+}
+
+func (t *testLint) TestIgnoredByMerge() {
+	config := &testLintIgnoredConfig{}
+	manager := t.newManager(config)
+	t.Len(manager.Lint(), 2)
+
+	executeTestManager(t.T(), manager, "--level", "debug")
+
+	_, err := manager.Merge()
+	t.NoError(err)
+	t.Equal("debug", config.Level)
+}
+
+func (t *testLint) TestSplitHookMethodName() {
+	hook, field, matched, isHook := SplitHookMethodName("ParseEnvLevel", []string{"Level"})
+	t.Equal("ParseEnv", hook)
+	t.Equal("Level", field)
+	t.True(matched)
+	t.True(isHook)
+
+	_, _, matched, isHook = SplitHookMethodName("Validated", []string{"Level"})
+	t.False(matched)
+	t.False(isHook)
+}
+
+func TestLint(t *testing.T) {
+	suite.Run(t, new(testLint))
+}
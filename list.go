@@ -0,0 +1,251 @@
+package cvc
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cast"
+)
+
+// resizeList resizes the list of groups to n elements; the existing elements
+// are kept.
+func (m *Manager) resizeList(list *Item, n int) {
+	s := reflect.MakeSlice(list.Value.Type(), n, n)
+	reflect.Copy(s, list.Value)
+	list.Value.Set(s)
+
+	m.reparseElements(list)
+}
+
+// reparseElements parses the elements of the list or map of groups again,
+// because the elements may be added or moved. The defaults are applied to the
+// new elements.
+func (m *Manager) reparseElements(group *Item) {
+	for k, item := range m.m {
+		if !item.inside(group) {
+			continue
+		}
+
+		delete(m.m, k)
+		delete(m.keys, strings.ToLower(k))
+		for _, a := range item.Aliases() {
+			delete(m.aliases, strings.ToLower(a))
+		}
+	}
+
+	var items map[string]*Item
+	if group.IsList {
+		items = parseListElements(m.c, group)
+	} else {
+		items = parseMapElements(m.c, group)
+	}
+
+	m.cacheEnvNames(items)
+
+	for k, item := range items {
+		m.m[k] = item
+		m.keys[strings.ToLower(k)] = item
+		for _, a := range item.Aliases() {
+			m.aliases[strings.ToLower(a)] = item
+		}
+
+		if item.IsGroup {
+			continue
+		}
+
+		if err := m.applyDefault(item); err != nil {
+			m.Logger().Error("failed to set default", "item", k, "error", err)
+		}
+		item.Default = item.flagDefaultValue(item.FlagType()).Interface()
+	}
+}
+
+// mergeList sets the list of groups from the sequence of config; the list is
+// resized to the length of the sequence.
+func (m *Manager) mergeList(list *Item, i interface{}) (string, error) {
+	key := m.group + "." + list.FullName()
+
+	elems, err := cast.ToSliceE(i)
+	if err != nil {
+		return key, &ParseError{Key: list.FullName(), Source: SourceConfig, Input: i, Err: err}
+	}
+
+	m.resizeList(list, len(elems))
+
+	settings := map[string]interface{}{}
+	setConfigValue(settings, strings.Split(list.FullName(), "."), elems)
+	m.addMapEntriesFromConfig(settings)
+
+	for j, e := range elems {
+		em, err := cast.ToStringMapE(e)
+		if err != nil {
+			return key + "." + strconv.Itoa(j), &ParseError{
+				Key:    list.FullName() + "." + strconv.Itoa(j),
+				Source: SourceConfig,
+				Input:  e,
+				Err:    err,
+			}
+		}
+
+		values := map[string]interface{}{}
+		flattenConfig("", em, values)
+
+		var names []string
+		for k := range values {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+
+		for _, k := range names {
+			name := list.FullName() + "." + strconv.Itoa(j) + "." + k
+
+			item, isAlias, found := m.configItem(name)
+			if !found || isAlias {
+				return m.group + "." + name, &UnknownKeyError{Key: m.group + "." + name, Source: SourceConfig}
+			}
+
+			if item.IsList {
+				if n, err := m.mergeList(item, values[k]); err != nil {
+					return n, err
+				}
+				continue
+			}
+
+			a, err := item.parse(values[k])
+			if err != nil {
+				return m.group + "." + name, &ParseError{Key: item.FullName(), Source: SourceConfig, Input: values[k], Err: err}
+			}
+			if err := m.setRaw(item.FullName(), a); err != nil {
+				return m.group + "." + name, &ParseError{Key: item.FullName(), Source: SourceConfig, Input: values[k], Err: err}
+			}
+		}
+	}
+
+	return "", nil
+}
+
+// growFromEnv appends the elements to the lists of groups while the env of
+// the next index, like `NARU_UPSTREAMS_2_HOST`, is found, and adds the entries
+// to the maps of groups by the envs, like `NARU_DATABASES_PRIMARY_DSN`.
+func (m *Manager) growFromEnv() {
+	for {
+		var groups []*Item
+		for _, item := range m.m {
+			if item.IsList || item.IsMap {
+				groups = append(groups, item)
+			}
+		}
+		sort.Slice(groups, func(i, j int) bool {
+			return groups[i].FullName() < groups[j].FullName()
+		})
+
+		var grown bool
+		for _, group := range groups {
+			if group.IsMap {
+				if keys := m.mapKeysFromEnv(group); len(keys) > 0 {
+					m.addMapEntries(group, keys)
+					grown = true
+					break
+				}
+				continue
+			}
+
+			n := group.Value.Len()
+			for m.hasListElementEnv(group, n) {
+				n++
+			}
+
+			if n > group.Value.Len() {
+				m.resizeList(group, n)
+				grown = true
+				break
+			}
+		}
+
+		if !grown {
+			return
+		}
+	}
+}
+
+func (m *Manager) hasListElementEnv(list *Item, i int) bool {
+	ev := reflect.New(list.Value.Type().Elem()).Elem()
+	_, items := parseListElement(m.c, list, i, ev)
+
+	for _, item := range items {
+		if item.IsGroup {
+			continue
+		}
+		if _, found := m.envLookupFunc(m.EnvName(item)); found {
+			return true
+		}
+	}
+
+	return false
+}
+
+// listConfigValue returns the list of groups as the sequence of maps.
+func listConfigValue(list *Item) []interface{} {
+	s := []interface{}{}
+	for _, elem := range list.Children {
+		s = append(s, groupConfigValue(elem))
+	}
+
+	return s
+}
+
+func groupConfigValue(group *Item) map[string]interface{} {
+	c := map[string]interface{}{}
+	for _, item := range group.Children {
+		k := item.FullName()
+		k = k[strings.LastIndex(k, ".")+1:]
+
+		switch {
+		case item.IsList:
+			c[k] = listConfigValue(item)
+		case item.IsGroup:
+			c[k] = groupConfigValue(item)
+		default:
+			c[k] = item.configValue()
+		}
+	}
+
+	return c
+}
+
+// mergeHCLBlocks merges the blocks of HCL, which are decoded as the lists of
+// maps, into the maps, except for the lists of groups.
+func (m *Manager) mergeHCLBlocks(key string, i interface{}) interface{} {
+	switch t := i.(type) {
+	case map[string]interface{}:
+		c := map[string]interface{}{}
+		for k, v := range t {
+			n := k
+			if len(key) > 0 {
+				n = key + "." + k
+			}
+			c[k] = m.mergeHCLBlocks(n, v)
+		}
+		return c
+	case []map[string]interface{}:
+		if item, _, found := m.configItem(key); found && item.IsList {
+			l := make([]interface{}, len(t))
+			for j := range t {
+				l[j] = m.mergeHCLBlocks(key+"."+strconv.Itoa(j), t[j])
+			}
+			return l
+		}
+
+		c := map[string]interface{}{}
+		for _, e := range t {
+			for k, v := range e {
+				c[k] = v
+			}
+		}
+		return m.mergeHCLBlocks(key, c)
+	default:
+		return i
+	}
+}
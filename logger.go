@@ -0,0 +1,100 @@
+package cvc
+
+import (
+	"context"
+	"log/slog"
+
+	logging "github.com/inconshreveable/log15"
+)
+
+// Logger is the logger of cvc; ctx is the list of key and value pairs.
+type Logger interface {
+	Debug(msg string, ctx ...interface{})
+	Info(msg string, ctx ...interface{})
+	Warn(msg string, ctx ...interface{})
+	Error(msg string, ctx ...interface{})
+}
+
+// NopLogger discards the logs; it is the default Logger.
+type NopLogger struct{}
+
+func (l NopLogger) Debug(string, ...interface{}) {}
+func (l NopLogger) Info(string, ...interface{})  {}
+func (l NopLogger) Warn(string, ...interface{})  {}
+func (l NopLogger) Error(string, ...interface{}) {}
+
+type log15Logger struct {
+	l logging.Logger
+}
+
+// NewLog15Logger wraps the log15 logger.
+func NewLog15Logger(l logging.Logger) Logger {
+	return log15Logger{l: l}
+}
+
+func (l log15Logger) Debug(msg string, ctx ...interface{}) { l.l.Debug(msg, ctx...) }
+func (l log15Logger) Info(msg string, ctx ...interface{})  { l.l.Info(msg, ctx...) }
+func (l log15Logger) Warn(msg string, ctx ...interface{})  { l.l.Warn(msg, ctx...) }
+func (l log15Logger) Error(msg string, ctx ...interface{}) { l.l.Error(msg, ctx...) }
+
+type slogLogger struct {
+	l *slog.Logger
+}
+
+// NewSlogLogger wraps the slog logger.
+func NewSlogLogger(l *slog.Logger) Logger {
+	return slogLogger{l: l}
+}
+
+func (l slogLogger) Debug(msg string, ctx ...interface{}) { l.log(slog.LevelDebug, msg, ctx) }
+func (l slogLogger) Info(msg string, ctx ...interface{})  { l.log(slog.LevelInfo, msg, ctx) }
+func (l slogLogger) Warn(msg string, ctx ...interface{})  { l.log(slog.LevelWarn, msg, ctx) }
+func (l slogLogger) Error(msg string, ctx ...interface{}) { l.log(slog.LevelError, msg, ctx) }
+
+func (l slogLogger) log(level slog.Level, msg string, ctx []interface{}) {
+	l.l.Log(context.Background(), level, msg, ctx...)
+}
+
+type contextLogger struct {
+	l   Logger
+	ctx []interface{}
+}
+
+func newContextLogger(l Logger, ctx ...interface{}) Logger {
+	return contextLogger{l: l, ctx: ctx}
+}
+
+func (l contextLogger) Debug(msg string, ctx ...interface{}) { l.l.Debug(msg, l.with(ctx)...) }
+func (l contextLogger) Info(msg string, ctx ...interface{})  { l.l.Info(msg, l.with(ctx)...) }
+func (l contextLogger) Warn(msg string, ctx ...interface{})  { l.l.Warn(msg, l.with(ctx)...) }
+func (l contextLogger) Error(msg string, ctx ...interface{}) { l.l.Error(msg, l.with(ctx)...) }
+
+func (l contextLogger) with(ctx []interface{}) []interface{} {
+	return append(append([]interface{}{}, l.ctx...), ctx...)
+}
+
+type loggerValue struct {
+	Logger
+}
+
+// WithLogger sets the Logger of Manager.
+func WithLogger(l Logger) Option {
+	return func(m *Manager) {
+		m.SetLogger(l)
+	}
+}
+
+// SetLogger sets the Logger of Manager; nil restores the package logger, which
+// is set by SetLogging.
+func (m *Manager) SetLogger(l Logger) {
+	m.logger.Store(loggerValue{Logger: l})
+}
+
+// Logger returns the Logger of Manager.
+func (m *Manager) Logger() Logger {
+	if v, ok := m.logger.Load().(loggerValue); ok && v.Logger != nil {
+		return v.Logger
+	}
+
+	return log
+}
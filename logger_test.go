@@ -0,0 +1,100 @@
+package cvc
+
+import (
+	"bytes"
+	"log/slog"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type testCaptureLogger struct {
+	sync.Mutex
+	logs []string
+}
+
+func (l *testCaptureLogger) add(level, msg string) {
+	l.Lock()
+	defer l.Unlock()
+
+	l.logs = append(l.logs, level+": "+msg)
+}
+
+func (l *testCaptureLogger) Debug(msg string, ctx ...interface{}) { l.add("debug", msg) }
+func (l *testCaptureLogger) Info(msg string, ctx ...interface{})  { l.add("info", msg) }
+func (l *testCaptureLogger) Warn(msg string, ctx ...interface{})  { l.add("warn", msg) }
+func (l *testCaptureLogger) Error(msg string, ctx ...interface{}) { l.add("error", msg) }
+
+type testLogger struct {
+	suite.Suite
+}
+
+func (t *testLogger) newManager(options ...Option) *Manager {
+	config := &testConfig{A: 1, B: "b"}
+
+	return newTestManager(t.T(), config, map[string]string{"NARU_A": "a"}, []string{}, options...)
+}
+
+func (t *testLogger) TestDefault() {
+	manager := t.newManager()
+	t.Equal(log, manager.Logger())
+}
+
+func (t *testLogger) TestWithLogger() {
+	l := &testCaptureLogger{}
+	manager := t.newManager(WithLogger(l))
+
+	_, err := manager.Merge()
+	t.Error(err)
+	t.Contains(l.logs, "error: failed to parse env")
+}
+
+func (t *testLogger) TestSetLogger() {
+	manager := t.newManager()
+
+	l := &testCaptureLogger{}
+	manager.SetLogger(l)
+
+	_, err := manager.Merge()
+	t.Error(err)
+	t.Contains(l.logs, "debug: trying to merge")
+	t.Equal(l, manager.root.Logger())
+
+	manager.SetLogger(nil)
+	t.Equal(log, manager.Logger())
+}
+
+func (t *testLogger) TestSlog() {
+	var b bytes.Buffer
+	l := slog.New(slog.NewTextHandler(&b, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	manager := t.newManager(WithLogger(NewSlogLogger(l)))
+
+	_, err := manager.Merge()
+	t.Error(err)
+	t.Contains(b.String(), `level=ERROR msg="failed to parse env" item=NARU_A`)
+	t.NotContains(b.String(), "level=DEBUG")
+}
+
+type testLoggerItemConfig struct {
+	A string `env:"a.b"`
+	B string
+}
+
+func (c *testLoggerItemConfig) ParseB(i string) (string, string) {
+	return i, "not error"
+}
+
+func (t *testLogger) TestItem() {
+	l := &testCaptureLogger{}
+	manager := newTestManager(t.T(), &testLoggerItemConfig{}, nil, []string{"--b", "b"}, WithLogger(l))
+
+	_, err := manager.Merge()
+	t.Error(err)
+	t.Contains(l.logs, "error: invalid env name found")
+	t.Contains(l.logs, "error: ParseXX() return is not error type")
+}
+
+func TestLogger(t *testing.T) {
+	suite.Run(t, new(testLogger))
+}
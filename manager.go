@@ -3,19 +3,23 @@ package cvc
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"io/ioutil"
 	"os"
+	"path"
 	"path/filepath"
 	"reflect"
 	"sort"
 	"strings"
 	"sync"
-	"time"
+	"sync/atomic"
 
-	logging "github.com/inconshreveable/log15"
+	"github.com/spf13/cast"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
@@ -27,7 +31,7 @@ type viperConfig struct {
 	r      *bytes.Reader
 }
 
-func (c viperConfig) Reader() io.Reader {
+func (c *viperConfig) Reader() io.Reader {
 	c.Lock()
 	defer c.Unlock()
 
@@ -36,10 +40,35 @@ func (c viperConfig) Reader() io.Reader {
 	return bytes.NewReader(b)
 }
 
-func (c viperConfig) Keys(group string, v *viper.Viper) ([]string, error) {
-	return GetKeysFromViperConfig(group, c.format, v, c.Reader())
+func (c *viperConfig) Settings() (map[string]interface{}, error) {
+	nv := viper.New()
+	if c.format == "jsonc" || c.format == "json5" {
+		b, _ := ioutil.ReadAll(c.Reader())
+		v, err := parseJSON5(b)
+		if err != nil {
+			return nil, err
+		}
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("json5: config must be object, not %T", v)
+		}
+		if err := nv.MergeConfigMap(m); err != nil {
+			return nil, err
+		}
+
+		return nv.AllSettings(), nil
+	}
+
+	nv.SetConfigType(c.format)
+	if err := nv.ReadConfig(c.Reader()); err != nil {
+		return nil, err
+	}
+
+	return nv.AllSettings(), nil
 }
 
+const ConfigVersionKey = "version"
+
 type Manager struct {
 	sync.RWMutex
 	name          string
@@ -48,16 +77,100 @@ type Manager struct {
 	cmd           *cobra.Command
 	m             map[string]*Item
 	fs            map[string]*Item
+	keys          map[string]*Item
+	aliases       map[string]*Item
 	root          *Item
-	viperConfigs  []viperConfig
+	viperConfigs  []*viperConfig
 	envLookupFunc func(string) (string, bool)
+	environFunc   func() []string
+	stdin         io.Reader
 	useEnv        bool
 	group         string
 	groups        []string
+	snapshot      atomic.Value
+	version       uint64
+	migrations    map[int]func(map[string]interface{}) error
+	naming        NamingStrategy
+
+	envPrefix        *string
+	omitRootEnvGroup bool
+	hooks            map[string][]func(*Manager) error
+	validators       []func(*Snapshot) error
+	variants         variantTypes
+	configFlags      *configFlags
+	templates        map[string]reflect.Value
+	logger           atomic.Value
+	defaultErr       error
+	keyErr           error
+	deprecated       []string
+}
+
+type Option func(*Manager)
+
+func WithNamingStrategy(naming NamingStrategy) Option {
+	return func(m *Manager) {
+		m.naming = naming
+	}
+}
+
+// WithEnvPrefix sets the prefix of env names instead of the name of Manager;
+// the empty prefix drops it.
+func WithEnvPrefix(prefix string) Option {
+	return func(m *Manager) {
+		m.envPrefix = &prefix
+	}
+}
+
+// WithoutRootEnvGroup drops the command group from env names for the root
+// command, like `NARU_LOG_FILE` instead of `NARU_NARU_LOG_FILE`.
+func WithoutRootEnvGroup() Option {
+	return func(m *Manager) {
+		m.omitRootEnvGroup = true
+	}
+}
+
+// WithConfigFlags adds the `--config` and `--config-format` flags. The config
+// files of `--config` are loaded by Merge, and `--config -` reads the config
+// from stdin in the format of `--config-format`.
+func WithConfigFlags() Option {
+	return WithConfigFlagNames("config", "config-format")
+}
+
+// WithConfigFlagNames adds the config flags like WithConfigFlags with the
+// names, for the config, which already has the `config` field.
+func WithConfigFlagNames(file, format string) Option {
+	return func(m *Manager) {
+		m.configFlags = &configFlags{fileFlag: file, formatFlag: format}
+	}
+}
+
+type configFlags struct {
+	fileFlag   string
+	formatFlag string
+	files      []string
+	format     string
+	loaded     bool
+	err        error
 }
 
-func NewManager(name string, c interface{}, cmd *cobra.Command, v *viper.Viper) *Manager {
-	root, m := parseConfig(c)
+func NewManager(name string, c interface{}, cmd *cobra.Command, v *viper.Viper, options ...Option) *Manager {
+	manager := &Manager{
+		name:          name,
+		c:             c,
+		cmd:           cmd,
+		v:             v,
+		envLookupFunc: os.LookupEnv,
+		environFunc:   os.Environ,
+		stdin:         os.Stdin,
+		useEnv:        true,
+		naming:        DefaultNaming{},
+	}
+
+	for _, option := range options {
+		option(manager)
+	}
+
+	root, m := parseConfig(c, manager.naming, nil)
 
 	var groups []string
 	thisCmd := cmd
@@ -76,86 +189,198 @@ func NewManager(name string, c interface{}, cmd *cobra.Command, v *viper.Viper)
 
 	group := strings.Join(groups, "-")
 
-	if verbose {
-		var envs []string
-		for _, item := range m {
-			envs = append(envs, item.EnvName(group))
+	names := make([]string, 0, len(m))
+	for k := range m {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	fs := map[string]*Item{}
+	keys := map[string]*Item{}
+	aliases := map[string]*Item{}
+	for _, k := range names {
+		i := m[k]
+		if !i.inCollection() {
+			fs[i.FlagName()] = i
 		}
 
-		log.Debug("available envs:", "env", envs)
+		// viper lowercases the config keys, so the keys, like `fileName` and
+		// `filename`, can not be distinguished.
+		lk := strings.ToLower(k)
+		if o, found := keys[lk]; found && manager.keyErr == nil {
+			manager.keyErr = &SourceError{
+				Key:    k,
+				Source: SourceConfig,
+				Err:    fmt.Errorf("'%s' and '%s' are the same key in config", o.FullName(), k),
+			}
+		}
+		keys[lk] = i
+		for _, a := range i.Aliases() {
+			aliases[strings.ToLower(a)] = i
+		}
 	}
 
-	fs := map[string]*Item{}
-	for _, i := range m {
-		fs[i.FlagName()] = i
+	manager.m = m
+	manager.fs = fs
+	manager.keys = keys
+	manager.aliases = aliases
+	manager.root = root
+	manager.root.logger = manager.Logger
+	manager.group = group
+	manager.groups = groups
+	manager.cacheEnvNames(m)
+
+	if verbose {
+		manager.Logger().Debug("available envs:", "env", manager.Envs())
 	}
 
-	manager := &Manager{
-		name:          name,
-		c:             c,
-		cmd:           cmd,
-		v:             v,
-		m:             m,
-		fs:            fs,
-		root:          root,
-		envLookupFunc: os.LookupEnv,
-		useEnv:        true,
-		group:         group,
-		groups:        groups,
+	for _, item := range manager.Map() {
+		if err := manager.setDefault(item); err != nil {
+			manager.Logger().Error("failed to set default", "item", item.FullName(), "error", err)
+		}
 	}
 
 	for _, item := range manager.Map() {
 		manager.setFlag(item)
 	}
 
+	if c := manager.configFlags; c != nil {
+		for _, n := range []string{c.fileFlag, c.formatFlag} {
+			if cmd.Flags().Lookup(n) != nil {
+				c.err = fmt.Errorf("flag '%s' of config is already defined; set the other name by WithConfigFlagNames", n)
+				break
+			}
+		}
+
+		if c.err == nil {
+			cmd.Flags().StringArrayVar(&c.files, c.fileFlag, nil, "config file; '-' reads from stdin")
+			cmd.Flags().StringVar(&c.format, c.formatFlag, "", "format of config; default is by the extension of file")
+		}
+	}
+
+	manager.storeSnapshot()
+
 	return manager
 }
 
 func (m *Manager) Merge() (string, error) {
+	return m.MergeContext(context.Background())
+}
+
+// MergeContext merges like Merge, but it stops when ctx is done. ctx is
+// passed to the `Validate(context.Context) error` methods.
+func (m *Manager) MergeContext(ctx context.Context) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	if t, err := m.invalidDefault(); err != nil {
+		return t, err
+	}
+
+	if t, err := m.conflictedKey(); err != nil {
+		return t, err
+	}
+
+	m.Lock()
+	m.deprecated = nil
+	m.cacheEnvNames(m.m)
+	m.Unlock()
+
+	if err := m.loadConfigFlags(); err != nil {
+		m.Logger().Error("failed to load config", "error", err)
+		return "config", err
+	}
+
+	if t, err := m.runHook(HookBeforeMerge); err != nil {
+		m.Logger().Error("failed to run hook", "stage", HookBeforeMerge, "item", t, "error", err)
+		return t, err
+	}
+
 	if m.UseEnv() {
 		p, err := m.MergeFromEnv()
 		if err != nil {
-			log.Error("failed to parse env", "item", p, "error", err)
+			m.Logger().Error("failed to parse env", "item", p, "error", err)
 			return p, err
 		}
+	}
 
-		if t, err := m.root.Validate(); err != nil {
-			log.Error("failed to validate env", "item", p, "error", err)
-			return t, err
+	if t, err := m.runHook(HookAfterEnv); err != nil {
+		m.Logger().Error("failed to run hook", "stage", HookAfterEnv, "item", t, "error", err)
+		return t, err
+	}
+
+	if m.UseEnv() {
+		if t, err := m.root.ValidateContext(ctx); err != nil {
+			m.Logger().Error("failed to validate env", "item", t, "error", err)
+			return t, m.validationError(t, SourceEnv, err)
 		}
 	}
 
 	{
 		p, err := m.MergeFromViper()
 		if err != nil {
-			log.Error("failed to parse config", "item", p, "error", err)
+			m.Logger().Error("failed to parse config", "item", p, "error", err)
 			return p, err
 		}
 
-		if t, err := m.root.Validate(); err != nil {
-			log.Error("failed to validate config", "item", t, "error", err)
+		if t, err := m.runHook(HookAfterConfigFile); err != nil {
+			m.Logger().Error("failed to run hook", "stage", HookAfterConfigFile, "item", t, "error", err)
 			return t, err
 		}
+
+		if t, err := m.root.ValidateContext(ctx); err != nil {
+			m.Logger().Error("failed to validate config", "item", t, "error", err)
+			return t, m.validationError(t, SourceConfig, err)
+		}
 	}
 
 	{
 		p, err := m.MergeFromFlags()
 		if err != nil {
-			log.Error("failed to parse flag", "flag", p, "error", err)
+			m.Logger().Error("failed to parse flag", "flag", p, "error", err)
 			return p, err
 		}
 
-		if t, err := m.root.Validate(); err != nil {
-			log.Error("failed to validate flag", "flag", t, "error", err)
+		if t, err := m.runHook(HookAfterFlags); err != nil {
+			m.Logger().Error("failed to run hook", "stage", HookAfterFlags, "item", t, "error", err)
 			return t, err
 		}
+
+		if t, err := m.root.ValidateContext(ctx); err != nil {
+			m.Logger().Error("failed to validate flag", "flag", t, "error", err)
+			return t, m.validationError(t, SourceFlag, err)
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	// the new elements of lists and maps may have the invalid default
+	if t, err := m.invalidDefault(); err != nil {
+		return t, err
 	}
 
 	if t, err := m.root.Merge(); err != nil {
-		log.Error("failed to merge", "item", t, "error", err)
+		m.Logger().Error("failed to merge", "item", t, "error", err)
+		return t, err
+	}
+
+	if t, err := m.runValidators(); err != nil {
+		m.Logger().Error("failed to validate merged config", "key", t, "error", err)
 		return t, err
 	}
 
+	if t, err := m.runHook(HookFinalize); err != nil {
+		m.Logger().Error("failed to run hook", "stage", HookFinalize, "item", t, "error", err)
+		return t, err
+	}
+
+	m.Lock()
+	m.storeSnapshot()
+	m.Unlock()
+
 	return "", nil
 }
 
@@ -163,24 +388,49 @@ func (m *Manager) MergeFromEnv() (string, error) {
 	m.Lock()
 	defer m.Unlock()
 
-	log_ := log.New(logging.Ctx{"type": "env"})
+	log_ := newContextLogger(m.Logger(), "type", "env")
 	log_.Debug("trying to merge")
 
+	m.growFromEnv()
+
 	for _, item := range m.m {
 		env := m.EnvName(item)
+		newEnv := env
 		input, found := m.envLookupFunc(env)
+
+		for _, names := range item.aliasNames() {
+			aliasEnv := item.Naming().EnvName(m.envNamePrefix(), names)
+			aliasInput, aliasFound := m.envLookupFunc(aliasEnv)
+			if !aliasFound {
+				continue
+			}
+
+			log_.Warn("deprecated env found", "name", aliasEnv, "new", newEnv)
+			m.deprecate(aliasEnv, newEnv)
+			if found && input != aliasInput {
+				return aliasEnv, &SourceError{
+					Key:    item.FullName(),
+					Source: SourceEnv,
+					Input:  aliasInput,
+					Err:    fmt.Errorf("both '%s' and '%s' are set with different values", aliasEnv, env),
+				}
+			}
+
+			env, input, found = aliasEnv, aliasInput, true
+		}
+
 		if !found {
 			continue
 		}
-		log.Debug("env found", "name", env, "value", input)
+		m.Logger().Debug("env found", "name", env, "value", input)
 		v, err := item.ParseEnv(input)
 		if err != nil {
-			return env, err
+			return env, &ParseError{Key: item.FullName(), Source: SourceEnv, Input: input, Err: err}
 		}
 
 		if err := m.setRaw(item.FullName(), v); err != nil {
 			log_.Error("failed to merge", "env", env, "value", input, "error", err)
-			return env, err
+			return env, &ParseError{Key: item.FullName(), Source: SourceEnv, Input: input, Err: err}
 		}
 	}
 
@@ -191,7 +441,7 @@ func (m *Manager) MergeFromFlags() (string, error) {
 	m.Lock()
 	defer m.Unlock()
 
-	log_ := log.New(logging.Ctx{"type": "flag"})
+	log_ := newContextLogger(m.Logger(), "type", "flag")
 	log_.Debug("trying to merge")
 
 	var err error
@@ -201,29 +451,68 @@ func (m *Manager) MergeFromFlags() (string, error) {
 			return
 		}
 
-		if !f.Changed {
+		if !f.Changed || m.isConfigFlag(f.Name) {
 			return
 		}
 
 		item, found := m.itemByFlag(f.Name)
 		if !found {
 			problemFlag = f.Name
-			err = fmt.Errorf("unknown flag found: '%s'", f.Name)
+			err = &UnknownKeyError{Key: f.Name, Source: SourceFlag}
 			return
 		}
 
 		input := reflect.ValueOf(item.Input).Elem().Interface()
+		if f.Name != item.FlagName() {
+			input = reflect.ValueOf(item.aliasInputs[f.Name]).Elem().Interface()
+			log_.Warn("deprecated flag found", "flag", f.Name, "new", item.FlagName())
+			m.deprecate("--"+f.Name, "--"+item.FlagName())
+
+			if n := m.cmd.Flags().Lookup(item.FlagName()); n != nil && n.Changed {
+				if !reflect.DeepEqual(input, reflect.ValueOf(item.Input).Elem().Interface()) {
+					problemFlag = f.Name
+					err = &SourceError{
+						Key:    item.FullName(),
+						Source: SourceFlag,
+						Input:  input,
+						Err:    fmt.Errorf("both '%s' and '%s' are set with different values", f.Name, item.FlagName()),
+					}
+				}
+				return
+			}
+
+			for name, other := range item.aliasInputs {
+				if name == f.Name {
+					continue
+				}
+				if n := m.cmd.Flags().Lookup(name); n == nil || !n.Changed {
+					continue
+				}
+				if !reflect.DeepEqual(input, reflect.ValueOf(other).Elem().Interface()) {
+					problemFlag = f.Name
+					err = &SourceError{
+						Key:    item.FullName(),
+						Source: SourceFlag,
+						Input:  input,
+						Err:    fmt.Errorf("both '%s' and '%s' are set with different values", f.Name, name),
+					}
+					return
+				}
+			}
+		}
 
 		var a interface{}
-		a, err = item.Parse(input)
+		a, err = item.parse(input)
 		log_.Debug("parsed", "flag", f.Name, "value", input, "error", err)
 		if err != nil {
 			problemFlag = f.Name
+			err = &ParseError{Key: item.FullName(), Source: SourceFlag, Input: input, Err: err}
 			return
 		}
-		if err := m.setRaw(item.FullName(), a); err != nil {
+		if err = m.setRaw(item.FullName(), a); err != nil {
 			problemFlag = f.Name
 			log_.Error("failed to merge", "flag", f.Name, "value", input, "error", err)
+			err = &ParseError{Key: item.FullName(), Source: SourceFlag, Input: input, Err: err}
 			return
 		}
 		log_.Debug("item merged", "flag", f.Name, "value", input)
@@ -237,7 +526,7 @@ func (m *Manager) MergeFromViper() (string, error) {
 	m.Lock()
 	defer m.Unlock()
 
-	log_ := log.New(logging.Ctx{"type": "viper"})
+	log_ := newContextLogger(m.Logger(), "type", "viper")
 
 	log_.Debug("trying to merge")
 	if len(m.viperConfigs) < 1 {
@@ -247,19 +536,61 @@ func (m *Manager) MergeFromViper() (string, error) {
 
 	var inserted []string
 	for _, c := range m.viperConfigs {
-		is, err := c.Keys(m.group, m.v)
-		if err != nil {
-			return "", err
-		} else if len(is) < 1 {
+		var settings map[string]interface{}
+		var err error
+		if isEnvFormat(c.format) {
+			if settings, err = m.envFileSettings(c.Reader()); err != nil {
+				var e *UnknownKeyError
+				if errors.As(err, &e) {
+					return e.Key, err
+				}
+				return "", &SourceError{Source: SourceConfig, Err: err}
+			}
+		} else if settings, err = c.Settings(); err != nil {
+			return "", &SourceError{Source: SourceConfig, Err: err}
+		}
+
+		group, found := settings[m.group]
+		if !found {
 			log_.Debug("no config values found")
 			continue
 		}
-		log_.Debug("keys loaded", "keys", is)
 
-		for _, i := range is {
+		if c.format == "hcl" {
+			group = m.mergeHCLBlocks("", group)
+		}
+
+		groupSettings := cast.ToStringMap(group)
+		if err := m.migrate(groupSettings); err != nil {
+			return m.group + "." + ConfigVersionKey, &SourceError{
+				Key:    ConfigVersionKey,
+				Source: SourceConfig,
+				Input:  groupSettings[ConfigVersionKey],
+				Err:    err,
+			}
+		}
+		settings[m.group] = groupSettings
+
+		m.addMapEntriesFromConfig(groupSettings)
+
+		keys := map[string]interface{}{}
+		flattenConfig("", groupSettings, keys)
+		for k := range keys {
+			if _, _, found := m.configItem(k); !found {
+				if k == ConfigVersionKey {
+					continue
+				}
+
+				i, found := m.interfaceKey(k)
+				if !found {
+					return m.group + "." + k, &UnknownKeyError{Key: m.group + "." + k, Source: SourceConfig}
+				}
+				k = i
+			}
+
 			var found bool
 			for _, j := range inserted {
-				if i == j {
+				if k == j {
 					found = true
 					break
 				}
@@ -267,40 +598,70 @@ func (m *Manager) MergeFromViper() (string, error) {
 			if found {
 				continue
 			}
-			inserted = append(inserted, i)
+			inserted = append(inserted, k)
 		}
+		log_.Debug("keys loaded", "keys", inserted)
 
-		if err := m.v.ReadConfig(c.Reader()); err != nil {
-			return "", err
+		if err := m.v.MergeConfigMap(settings); err != nil {
+			return "", &SourceError{Source: SourceConfig, Input: settings, Err: err}
 		}
 	}
 
-	for _, k := range inserted {
-		ks := strings.SplitN(k, ".", 2)
-		if len(ks) < 2 {
-			log_.Warn("unknown key found", "key", k)
+	sort.Strings(inserted)
+
+	isInserted := map[string]bool{}
+	for _, key := range inserted {
+		isInserted[key] = true
+	}
+	aliasKeys := map[string]string{}
+
+	for _, key := range inserted {
+		k := m.group + "." + key
+
+		item, isAlias, _ := m.configItem(key)
+		if item.IsList {
+			if n, err := m.mergeList(item, m.v.Get(k)); err != nil {
+				log_.Error("failed to merge list", "key", k, "error", err)
+				return n, err
+			}
 			continue
 		}
-		if ks[0] != m.group {
+		if item.IsGroup {
 			continue
 		}
-		key := ks[1]
 
-		item, found := m.get(key)
-		if !found {
-			log_.Warn("unknown key found", "raw", k, "key", key)
-			continue
+		if isAlias {
+			newKey := m.group + "." + item.FullName()
+			log_.Warn("deprecated key found", "key", k, "new", newKey)
+			m.deprecate(k, newKey)
+
+			other, found := aliasKeys[item.FullName()]
+			if isInserted[strings.ToLower(item.FullName())] {
+				other, found = newKey, true
+			}
+			if found {
+				if !reflect.DeepEqual(m.v.Get(k), m.v.Get(other)) {
+					return k, &SourceError{
+						Key:    item.FullName(),
+						Source: SourceConfig,
+						Input:  m.v.Get(k),
+						Err:    fmt.Errorf("both '%s' and '%s' are set with different values", k, other),
+					}
+				}
+				continue
+			}
+			aliasKeys[item.FullName()] = k
 		}
 
-		a, err := item.Parse(m.v.Get(k))
+		a, err := item.parse(m.v.Get(k))
 		log_.Debug("parsed", "key", k, "value", m.v.Get(k), "error", err)
 		if err != nil {
 			log_.Error("failed to parse", "raw", k, "key", key, "error", err, "input", m.v.Get(k))
-			return k, err
+			return k, &ParseError{Key: item.FullName(), Source: SourceConfig, Input: m.v.Get(k), Err: err}
 		}
-		if err := m.setRaw(key, a); err != nil {
+		if err := m.setRaw(item.FullName(), a); err != nil {
 			log_.Error("failed to merge", "raw", k, "key", key, "value", m.v.Get(k), "error", err)
-			return k, err
+			return k, &ParseError{Key: item.FullName(), Source: SourceConfig, Input: m.v.Get(k), Err: err}
 		}
 		log_.Debug("item merged", "raw", k, "key", key, "value", a)
 	}
@@ -309,6 +670,82 @@ func (m *Manager) MergeFromViper() (string, error) {
 	return "", nil
 }
 
+// RegisterMigration registers the migration, which converts the config values
+// of the group from version `from` to `from + 1`. The version of config is
+// read from the `version` key of the group, and config without it is version
+// 0.
+func (m *Manager) RegisterMigration(from int, fn func(map[string]interface{}) error) error {
+	m.Lock()
+	defer m.Unlock()
+
+	if from < 0 {
+		return fmt.Errorf("invalid migration version: %d", from)
+	}
+	if _, found := m.migrations[from]; found {
+		return fmt.Errorf("migration already registered: %d", from)
+	}
+
+	if m.migrations == nil {
+		m.migrations = map[int]func(map[string]interface{}) error{}
+	}
+	m.migrations[from] = fn
+
+	return nil
+}
+
+func (m *Manager) ConfigVersion() int {
+	m.RLock()
+	defer m.RUnlock()
+
+	return m.configVersion()
+}
+
+func (m *Manager) configVersion() int {
+	var latest int
+	for from := range m.migrations {
+		if from+1 > latest {
+			latest = from + 1
+		}
+	}
+
+	return latest
+}
+
+func (m *Manager) migrate(settings map[string]interface{}) error {
+	if len(m.migrations) < 1 {
+		return nil
+	}
+
+	var version int
+	if v, found := settings[ConfigVersionKey]; found {
+		i, err := cast.ToIntE(v)
+		if err != nil {
+			return fmt.Errorf("invalid config version: %v", v)
+		}
+		version = i
+	}
+
+	latest := m.configVersion()
+	if version < 0 || version > latest {
+		return fmt.Errorf("unsupported config version: %d", version)
+	}
+
+	for ; version < latest; version++ {
+		fn, found := m.migrations[version]
+		if !found {
+			return fmt.Errorf("migration not found: %d", version)
+		}
+
+		if err := fn(settings); err != nil {
+			return err
+		}
+		m.Logger().Debug("config migrated", "from", version, "to", version+1)
+	}
+	settings[ConfigVersionKey] = latest
+
+	return nil
+}
+
 func (m *Manager) UseEnv() bool {
 	m.RLock()
 	defer m.RUnlock()
@@ -331,46 +768,176 @@ func (m *Manager) SetUseEnv(s bool) {
 	m.useEnv = s
 }
 
+// SetViperConfig sets the config in the format; the empty format is detected
+// by the content.
 func (m *Manager) SetViperConfig(format string, b []byte) error {
+	if len(format) < 1 {
+		f, err := detectFormat("", b)
+		if err != nil {
+			return &SourceError{Source: SourceConfig, Err: err}
+		}
+		format = f
+	}
+
 	m.Lock()
 	defer m.Unlock()
 
-	m.viperConfigs = append(m.viperConfigs, viperConfig{format: format, r: bytes.NewReader(b)})
+	m.viperConfigs = append(m.viperConfigs, &viperConfig{format: format, r: bytes.NewReader(b)})
 	return nil
 }
 
+// SetViperConfigReader reads the config from r in the format; the empty format
+// is detected by the content.
+func (m *Manager) SetViperConfigReader(format string, r io.Reader) error {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	if len(format) > 0 {
+		if format, err = configFormat(format); err != nil {
+			return &SourceError{Source: SourceConfig, Input: format, Err: err}
+		}
+	}
+
+	return m.SetViperConfig(format, b)
+}
+
+// SetViperConfigFile reads the config files; the format is by the extension
+// of file. "-" reads the config from stdin in the format of `--config-format`.
+// When one of files fails, none of them is added.
 func (m *Manager) SetViperConfigFile(fs ...string) error {
+	var format string
+	if m.configFlags != nil {
+		format = m.configFlags.format
+	}
+
+	var configs []*viperConfig
 	for _, f := range fs {
-		if err := m.setViperConfigFile(f); err != nil {
+		c, err := m.readViperConfigFile(f, format)
+		if err != nil {
 			return err
 		}
+		configs = append(configs, c)
 	}
 
+	m.Lock()
+	defer m.Unlock()
+
+	m.viperConfigs = append(m.viperConfigs, configs...)
+
 	return nil
 }
 
-func (m *Manager) setViperConfigFile(f string) error {
-	ext := strings.ToLower(filepath.Ext(f))
-	if len(ext) < 2 {
-		return fmt.Errorf("no filename extension")
-	}
-	var found bool
-	for _, e := range viper.SupportedExts {
-		if e == ext[1:] {
-			found = true
-			break
+// SetViperConfigFS reads the config files from fsys, like embed.FS and
+// fstest.MapFS; the format is by the extension of file or the content.
+func (m *Manager) SetViperConfigFS(fsys fs.FS, paths ...string) error {
+	for _, f := range paths {
+		b, err := fs.ReadFile(fsys, f)
+		if err != nil {
+			return &SourceError{Source: SourceConfig, Input: f, Err: err}
+		}
+
+		format, err := detectFormat(path.Ext(f), b)
+		if err != nil {
+			return &SourceError{Source: SourceConfig, Input: f, Err: err}
+		}
+
+		if err := m.SetViperConfig(format, b); err != nil {
+			return err
 		}
 	}
-	if !found {
-		return fmt.Errorf("unsupported file type found")
+
+	return nil
+}
+
+func (m *Manager) readViperConfigFile(f, format string) (*viperConfig, error) {
+	var b []byte
+	var err error
+	if f == "-" {
+		m.RLock()
+		stdin := m.stdin
+		m.RUnlock()
+
+		b, err = ioutil.ReadAll(stdin)
+	} else {
+		b, err = ioutil.ReadFile(f)
+	}
+	if err != nil {
+		return nil, &SourceError{Source: SourceConfig, Input: f, Err: err}
 	}
 
-	b, err := ioutil.ReadFile(f)
+	switch {
+	case len(format) > 0:
+		format, err = configFormat(format)
+	case f == "-":
+		format, err = detectFormat("", b)
+	default:
+		format, err = detectFormat(filepath.Ext(f), b)
+	}
 	if err != nil {
+		return nil, &SourceError{Source: SourceConfig, Input: f, Err: err}
+	}
+
+	return &viperConfig{format: format, r: bytes.NewReader(b)}, nil
+}
+
+// configFormat checks the format or the extension of file, like ".yml".
+func configFormat(format string) (string, error) {
+	format = strings.ToLower(strings.TrimPrefix(format, "."))
+	if len(format) < 1 {
+		return "", fmt.Errorf("no filename extension")
+	}
+
+	formats := make([]string, 0, len(viper.SupportedExts)+len(extraFormats))
+	formats = append(formats, viper.SupportedExts...)
+	formats = append(formats, extraFormats...)
+
+	for _, e := range formats {
+		if e == format {
+			return format, nil
+		}
+	}
+
+	return "", fmt.Errorf("unsupported file type found")
+}
+
+// detectFormat returns the format by the extension of file; when the
+// extension is missing or unknown, the format is detected by the content.
+func detectFormat(ext string, b []byte) (string, error) {
+	if format, err := configFormat(ext); err == nil {
+		return format, nil
+	}
+
+	if format := sniffFormat(b); len(format) > 0 {
+		return format, nil
+	}
+
+	return "", fmt.Errorf("unknown format of config")
+}
+
+// loadConfigFlags loads the config files of `--config` once.
+func (m *Manager) loadConfigFlags() error {
+	c := m.configFlags
+	switch {
+	case c == nil || c.loaded:
+		return nil
+	case c.err != nil:
+		return &SourceError{Source: SourceFlag, Err: c.err}
+	}
+
+	if err := m.SetViperConfigFile(c.files...); err != nil {
 		return err
 	}
+	c.loaded = true
+
+	return nil
+}
+
+func (m *Manager) isConfigFlag(name string) bool {
+	c := m.configFlags
 
-	return m.SetViperConfig(ext[1:], b)
+	return c != nil && c.err == nil && (name == c.fileFlag || name == c.formatFlag)
 }
 
 func (m *Manager) Root() *Item {
@@ -404,14 +971,45 @@ func (m *Manager) Envs() []string {
 }
 
 func (m *Manager) EnvName(item *Item) string {
-	prefix := m.group
-	if len(m.name) > 0 {
-		prefix = m.name + "-" + prefix
+	prefix := m.envNamePrefix()
+	if item.envCached && item.envPrefix == prefix {
+		return item.envName
 	}
 
 	return item.EnvName(prefix)
 }
 
+// cacheEnvNames caches the env names of the items; the prefix of env names is
+// changed when the command is added to the parent after NewManager, so the
+// env names are cached again by Merge.
+func (m *Manager) cacheEnvNames(items map[string]*Item) {
+	prefix := m.envNamePrefix()
+	for _, item := range items {
+		if item.IsGroup || (item.envCached && item.envPrefix == prefix) {
+			continue
+		}
+
+		item.envName, item.envPrefix, item.envCached = item.EnvName(prefix), prefix, true
+	}
+}
+
+func (m *Manager) envNamePrefix() string {
+	prefix := m.name
+	if m.envPrefix != nil {
+		prefix = *m.envPrefix
+	}
+
+	var names []string
+	if len(prefix) > 0 {
+		names = append(names, prefix)
+	}
+	if !m.omitRootEnvGroup || m.cmd.Parent() != nil {
+		names = append(names, m.group)
+	}
+
+	return strings.Join(names, "-")
+}
+
 func (m *Manager) ConfigPprint() (o []interface{}) {
 	m.RLock()
 	defer m.RUnlock()
@@ -440,7 +1038,20 @@ func (m *Manager) ConfigString() string {
 
 	b, err := json.MarshalIndent(m.c, "", "  ")
 	if err != nil {
-		log.Error("failed to marshal config", "error", err)
+		m.Logger().Error("failed to marshal config", "error", err)
+		return ""
+	}
+
+	// the optional items, which are not set, are omitted
+	omit := map[string]bool{}
+	for _, item := range m.m {
+		if item.isOptional() && item.Value.IsNil() {
+			omit[jsonPath(item)] = true
+		}
+	}
+
+	if b, err = omitJSONKeys(b, omit); err != nil {
+		m.Logger().Error("failed to marshal config", "error", err)
 		return ""
 	}
 
@@ -469,19 +1080,7 @@ func (m *Manager) ViperString(format string) (string, error) {
 	m.RLock()
 	defer m.RUnlock()
 
-	f, err := ioutil.TempFile("", fmt.Sprintf("cvc*.%s", format))
-	if err != nil {
-		return "", err
-	}
-	defer func() {
-		os.Remove(f.Name())
-	}()
-
-	if err = m.v.WriteConfigAs(f.Name()); err != nil {
-		return "", err
-	}
-
-	b, err := ioutil.ReadFile(f.Name())
+	b, err := m.encodeConfig(format, m.v.AllSettings())
 	if err != nil {
 		return "", err
 	}
@@ -510,6 +1109,66 @@ func (m *Manager) ViperString(format string) (string, error) {
 	return strings.TrimSpace(string(b)), nil
 }
 
+// WriteConfigFile writes the config of the group to the file atomically; when
+// format is empty, it is decided by the extension of path.
+func (m *Manager) WriteConfigFile(path, format string) error {
+	return m.writeConfigFile(path, format, false)
+}
+
+// WriteChangedConfigFile is like WriteConfigFile, but writes only the values,
+// which are different from the defaults.
+func (m *Manager) WriteChangedConfigFile(path, format string) error {
+	return m.writeConfigFile(path, format, true)
+}
+
+func (m *Manager) writeConfigFile(path, format string, nonDefault bool) error {
+	m.RLock()
+	defer m.RUnlock()
+
+	if len(format) < 1 {
+		format = strings.TrimPrefix(filepath.Ext(path), ".")
+	}
+
+	b, err := m.encodeConfig(format, m.configMap(nonDefault))
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(path, b)
+}
+
+func (m *Manager) configMap(nonDefault bool) map[string]interface{} {
+	c := map[string]interface{}{}
+	for k, item := range m.m {
+		if item.IsList && !item.inCollection() {
+			if l := listConfigValue(item); len(l) > 0 || !nonDefault {
+				setConfigValue(c, strings.Split(k, "."), l)
+			}
+			continue
+		}
+		if item.IsMap && !item.inCollection() {
+			if e := groupConfigValue(item); len(e) > 0 || !nonDefault {
+				setConfigValue(c, strings.Split(k, "."), e)
+			}
+			continue
+		}
+
+		if item.IsGroup || len(item.ViperName) < 1 || item.unselected() {
+			continue
+		}
+		if item.isOptional() && item.Value.IsNil() {
+			continue
+		}
+		if nonDefault && !item.Changed() {
+			continue
+		}
+
+		setConfigValue(c, strings.Split(k, "."), item.configValue())
+	}
+
+	return map[string]interface{}{m.group: c}
+}
+
 func (m *Manager) Map() map[string]*Item {
 	m.RLock()
 	defer m.RUnlock()
@@ -529,6 +1188,34 @@ func (m *Manager) get(key string) (*Item, bool) {
 	return c, found
 }
 
+// interfaceKey finds the interface item of the key, like `any` of `any.a`;
+// the map value of the interface item is flattened like the groups.
+func (m *Manager) interfaceKey(key string) (string, bool) {
+	keys := strings.Split(key, ".")
+	for i := len(keys) - 1; i > 0; i-- {
+		k := strings.Join(keys[:i], ".")
+		if item, _, found := m.configItem(k); found {
+			return k, !item.IsGroup && item.Value.Kind() == reflect.Interface
+		}
+	}
+
+	return "", false
+}
+
+// configItem finds the item by the key of config files; the keys from viper
+// are lowercased, so the keys are compared in lowercase.
+func (m *Manager) configItem(key string) (*Item, bool, bool) {
+	key = strings.ToLower(key)
+	if item, found := m.keys[key]; found {
+		return item, false, true
+	}
+	if item, found := m.aliases[key]; found {
+		return item, true, true
+	}
+
+	return nil, false, false
+}
+
 func (m *Manager) GetValue(key string, i interface{}) error {
 	m.RLock()
 	defer m.RUnlock()
@@ -539,36 +1226,41 @@ func (m *Manager) GetValue(key string, i interface{}) error {
 func (m *Manager) getValue(key string, i interface{}) error {
 	item, found := m.m[key]
 	if !found {
-		return fmt.Errorf("key not found")
+		return ErrorKeyNotFound.Clone().Set("key", key)
 	}
 
-	reflect.ValueOf(i).Elem().Set(item.Value)
-	return nil
+	return assignTo(key, item.Value, i)
 }
 
 func (m *Manager) SetValue(key string, i interface{}) error {
 	m.Lock()
 	defer m.Unlock()
 
-	return m.setValue(key, i)
+	if err := m.setValue(key, i); err != nil {
+		return err
+	}
+
+	m.storeSnapshot()
+	return nil
 }
 
 func (m *Manager) setValue(key string, i interface{}) error {
 	item, found := m.m[key]
 	if !found {
-		return fmt.Errorf("key not found: '%v'", key)
+		return ErrorKeyNotFound.Clone().Set("key", key)
 	}
 
-	r, err := item.Parse(i)
+	r, err := item.parse(i)
 	if err != nil {
 		return err
 	}
 
-	if !item.Value.Type().AssignableTo(reflect.TypeOf(r)) {
-		return fmt.Errorf("not assignable")
+	v, ok := inputValue(item.Value.Type(), r)
+	if !ok {
+		return ErrorNotAssignable.Clone().Set("key", key)
 	}
 
-	item.Value.Set(reflect.ValueOf(r))
+	item.set(v)
 	return nil
 }
 
@@ -576,27 +1268,53 @@ func (m *Manager) SetRaw(key string, i interface{}) error {
 	m.Lock()
 	defer m.Unlock()
 
-	return m.setRaw(key, i)
+	if err := m.setRaw(key, i); err != nil {
+		return err
+	}
+
+	m.storeSnapshot()
+	return nil
 }
 
 func (m *Manager) setRaw(key string, i interface{}) error {
 	item, found := m.m[key]
 	if !found {
-		return fmt.Errorf("key not found: '%v'", key)
+		return ErrorKeyNotFound.Clone().Set("key", key)
 	}
 
 	if !item.Value.CanSet() {
-		return fmt.Errorf("cannot set")
+		return ErrorNotAssignable.Clone().Set("key", key)
 	}
 
-	if !item.Value.Type().AssignableTo(reflect.TypeOf(i)) {
-		return fmt.Errorf("not assignable: %T - %T", item.Value.Interface(), i)
+	v, ok := inputValue(item.Value.Type(), i)
+	if !ok {
+		return ErrorNotAssignable.Clone().
+			Set("key", key).
+			Set("type", item.Value.Type().String()).
+			Set("value", fmt.Sprintf("%T", i))
 	}
 
-	item.Value.Set(reflect.ValueOf(i))
+	item.set(v)
 	return nil
 }
 
+// inputValue returns the value of i, which can be set to the item of t, like
+// string to interface{}; nil is the zero value of t, which can be nil.
+func inputValue(t reflect.Type, i interface{}) (reflect.Value, bool) {
+	if i == nil {
+		switch t.Kind() {
+		case reflect.Interface, reflect.Ptr, reflect.Map, reflect.Slice:
+			return reflect.Zero(t), true
+		default:
+			return reflect.Value{}, false
+		}
+	}
+
+	v := reflect.ValueOf(i)
+
+	return v, v.Type().AssignableTo(t)
+}
+
 func (m *Manager) SetEnvLookupFunc(fn func(string) (string, bool)) {
 	m.Lock()
 	defer m.Unlock()
@@ -604,6 +1322,23 @@ func (m *Manager) SetEnvLookupFunc(fn func(string) (string, bool)) {
 	m.envLookupFunc = fn
 }
 
+// SetStdin sets the reader of `--config -`; default is os.Stdin.
+func (m *Manager) SetStdin(r io.Reader) {
+	m.Lock()
+	defer m.Unlock()
+
+	m.stdin = r
+}
+
+// SetEnvironFunc sets the function, which lists the envs like os.Environ; the
+// envs are used to find the keys of the maps of groups.
+func (m *Manager) SetEnvironFunc(fn func() []string) {
+	m.Lock()
+	defer m.Unlock()
+
+	m.environFunc = fn
+}
+
 func (m *Manager) ItemByFlag(flag string) (*Item, bool) {
 	m.RLock()
 	defer m.RUnlock()
@@ -616,6 +1351,68 @@ func (m *Manager) itemByFlag(flag string) (*Item, bool) {
 	return c, found
 }
 
+func (m *Manager) setDefault(item *Item) error {
+	if item.IsGroup {
+		return nil
+	}
+
+	m.Lock()
+	defer m.Unlock()
+
+	return m.applyDefault(item)
+}
+
+func (m *Manager) applyDefault(item *Item) error {
+	tag, found := item.Tag.Lookup("default")
+	if !found || !item.Value.IsZero() {
+		return nil
+	}
+
+	v, err := item.parse(tag)
+	if err == nil {
+		err = m.setRaw(item.FullName(), v)
+	}
+	if err != nil {
+		e := &ParseError{Key: item.FullName(), Source: SourceDefault, Input: tag, Err: err}
+		if m.defaultErr == nil {
+			m.defaultErr = e
+		}
+
+		return e
+	}
+
+	return nil
+}
+
+func (m *Manager) deprecate(old, newName string) {
+	m.deprecated = append(m.deprecated, fmt.Sprintf("'%s' is deprecated; use '%s'", old, newName))
+}
+
+// invalidDefault returns the error of the first invalid `default` tag.
+func (m *Manager) invalidDefault() (string, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	if e, ok := m.defaultErr.(*ParseError); ok {
+		return e.Key, e
+	}
+
+	return "", nil
+}
+
+// conflictedKey returns the error of the config keys, which are same in lower
+// case.
+func (m *Manager) conflictedKey() (string, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	if e, ok := m.keyErr.(*SourceError); ok {
+		return e.Key, e
+	}
+
+	return "", nil
+}
+
 func (m *Manager) setFlag(item *Item) error {
 	if item.IsGroup {
 		return nil
@@ -624,11 +1421,20 @@ func (m *Manager) setFlag(item *Item) error {
 	m.Lock()
 	defer m.Unlock()
 
-	if len(item.FlagName()) < 1 {
+	t := item.FlagType()
+	defaultValue := item.flagDefaultValue(t)
+	item.Default = defaultValue.Interface()
+	if item.isOptional() && item.Value.IsNil() {
+		item.Default = nil
+	}
+
+	// the elements of list can be added by config and env, so they do not
+	// have flags
+	if len(item.FlagName()) < 1 || item.inCollection() {
 		return nil
 	}
 
-	call := func(t string, v interface{}, d reflect.Value) {
+	call := func(name string, v interface{}) {
 		if !item.EnableFlag() {
 			return
 		}
@@ -636,102 +1442,37 @@ func (m *Manager) setFlag(item *Item) error {
 		method, _ := GetMethodByName(m.cmd.Flags(), t, 4, 0)
 		method.Call(
 			reflect.ValueOf(v),
-			reflect.ValueOf(item.FlagName()),
-			d,
+			reflect.ValueOf(name),
+			defaultValue,
 			reflect.ValueOf(item.Tag.Get("flag-help")),
 		)
 		return
 	}
 
-	fns := GetFuncFromItem(item, "Parse", 1, 2)
-	t := getConfigTypeByFuncs(fns...)
-
-	if len(t) < 1 {
-		t = getConfigTypeByValue(item.Value)
+	input, err := newFlagInput(t)
+	if err != nil {
+		return err
 	}
-
-	var defaultValue reflect.Value = item.Value
-	if d, err := GetFlagValue(item); err == nil {
-		defaultValue = d
-	} else if t == "StringVar" {
-		method, found := GetMethodByName(item.Value.Interface(), "StringVar", 0, 1)
-		if !found {
-			defaultValue = reflect.ValueOf(fmt.Sprintf("%v", item.Value.Interface()))
-		} else {
-			vs := method.Call()
-			if len(vs) > 0 {
-				defaultValue = vs[0]
-			}
+	call(item.FlagName(), input)
+	item.Input = input
+
+	item.aliasInputs = map[string]interface{}{}
+	for _, names := range item.aliasNames() {
+		name := item.Naming().FlagName(names)
+		input, _ := newFlagInput(t)
+		call(name, input)
+		item.aliasInputs[name] = input
+		m.fs[name] = item
+
+		if item.EnableFlag() {
+			m.cmd.Flags().MarkDeprecated(name, fmt.Sprintf("use --%s instead", item.FlagName()))
 		}
 	}
 
-	switch t {
-	case "BoolVar":
-		var b *bool = new(bool)
-		call(t, b, defaultValue)
-		item.Input = b
-	case "IntVar":
-		var b *int = new(int)
-		call(t, b, defaultValue)
-		item.Input = b
-	case "Int8Var":
-		var b *int8 = new(int8)
-		call(t, b, defaultValue)
-		item.Input = b
-	case "Int16Var":
-		var b *int16 = new(int16)
-		call(t, b, defaultValue)
-		item.Input = b
-	case "Int32Var":
-		var b *int32 = new(int32)
-		call(t, b, defaultValue)
-		item.Input = b
-	case "Int64Var":
-		var b *int64 = new(int64)
-		call(t, b, defaultValue)
-		item.Input = b
-	case "UintVar":
-		var b *uint = new(uint)
-		call(t, b, defaultValue)
-		item.Input = b
-	case "Uint8Var":
-		var b *uint8 = new(uint8)
-		call(t, b, defaultValue)
-		item.Input = b
-	case "Uint16Var":
-		var b *uint16 = new(uint16)
-		call(t, b, defaultValue)
-		item.Input = b
-	case "Uint32Var":
-		var b *uint32 = new(uint32)
-		call(t, b, defaultValue)
-		item.Input = b
-	case "Uint64Var":
-		var b *uint64 = new(uint64)
-		call(t, b, defaultValue)
-		item.Input = b
-	case "Float32Var":
-		var b *float32 = new(float32)
-		call(t, b, defaultValue)
-		item.Input = b
-	case "Float64Var":
-		var b *float64 = new(float64)
-		call(t, b, defaultValue)
-		item.Input = b
-	case "StringVar":
-		var b *string = new(string)
-		call(t, b, defaultValue)
-		item.Input = b
-	case "DurationVar":
-		var b *time.Duration = new(time.Duration)
-		call(t, b, defaultValue)
-		item.Input = b
-	default:
-		return fmt.Errorf("value type, '%s' is not supported by flag", t)
-	}
-
 	viperName := m.group + "." + item.FullName()
-	m.v.SetDefault(viperName, defaultValue.Interface())
+	if !item.isOptional() || !item.Value.IsNil() {
+		m.v.SetDefault(viperName, defaultValue.Interface())
+	}
 
 	item.ViperName = viperName
 
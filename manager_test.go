@@ -1,11 +1,17 @@
 package cvc
 
 import (
+	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
+	"os"
+	"path/filepath"
 	"sort"
 	"strconv"
+	"strings"
 	"testing"
+	"testing/fstest"
 	"time"
 
 	"github.com/spf13/cobra"
@@ -218,7 +224,8 @@ func (t *testManager) TestValidate() {
 
 	manager := NewManager("", config, cmd, vp)
 	_, err := manager.Merge()
-	t.Equal(io.EOF, err)
+	t.True(errors.Is(err, io.EOF))
+	t.True(errors.Is(err, ErrorValidation))
 
 	{
 		var merged int
@@ -360,6 +367,41 @@ func (t *testManager) TestEnvs() {
 	t.Equal("NARU_B", envs[1])
 }
 
+func (t *testManager) TestEnvPrefix() {
+	newManager := func(parent bool, options ...Option) *Manager {
+		cmd := &cobra.Command{
+			Use:   "naru",
+			Short: "naru",
+		}
+		if parent {
+			root := &cobra.Command{Use: "naru"}
+			cmd.Use = "node"
+			root.AddCommand(cmd)
+		}
+		return NewManager("naru", &testConfig{}, cmd, viper.New(), options...)
+	}
+
+	t.Equal([]string{"NARU_NARU_A", "NARU_NARU_B"}, newManager(false).Envs())
+	t.Equal([]string{"NARU_A", "NARU_B"}, newManager(false, WithoutRootEnvGroup()).Envs())
+	t.Equal([]string{"APP_NARU_A", "APP_NARU_B"}, newManager(false, WithEnvPrefix("app")).Envs())
+	t.Equal([]string{"NARU_A", "NARU_B"}, newManager(false, WithEnvPrefix("")).Envs())
+	t.Equal([]string{"A", "B"}, newManager(false, WithEnvPrefix(""), WithoutRootEnvGroup()).Envs())
+
+	t.Equal([]string{"NARU_NODE_A", "NARU_NODE_B"}, newManager(true, WithoutRootEnvGroup()).Envs())
+	t.Equal([]string{"APP_NODE_A", "APP_NODE_B"}, newManager(true, WithEnvPrefix("app"), WithoutRootEnvGroup()).Envs())
+
+	manager := newManager(false, WithoutRootEnvGroup())
+	manager.SetEnvLookupFunc(func(s string) (string, bool) {
+		if s == "NARU_A" {
+			return "33", true
+		}
+		return "", false
+	})
+	_, err := manager.Merge()
+	t.NoError(err)
+	t.Equal(33, MustGet[int](manager, "a"))
+}
+
 func (t *testManager) TestFlagSet() {
 	config := &testConfig{
 		A: 1,
@@ -462,6 +504,641 @@ func (t *testManager) TestMergeFuncError() {
 	}
 }
 
+type testConfigWriteLog struct {
+	BaseGroup
+
+	File  string
+	Level string
+}
+
+type testConfigWrite struct {
+	BaseGroup
+
+	A   int
+	T   time.Duration
+	Log *testConfigWriteLog
+}
+
+func (t *testManager) newWriteManager() *Manager {
+	config := &testConfigWrite{
+		A: 1,
+		T: time.Second,
+		Log: &testConfigWriteLog{
+			File:  "naru.log",
+			Level: "debug",
+		},
+	}
+
+	return newTestManager(t.T(), config, nil, nil)
+}
+
+func (t *testManager) TestWriteConfigFile() {
+	dir, err := ioutil.TempDir("", "cvc")
+	t.NoError(err)
+	defer os.RemoveAll(dir)
+
+	manager := t.newWriteManager()
+	t.NoError(manager.SetValue("log.level", "error"))
+
+	for _, format := range []string{"yml", "json", "toml"} {
+		f := filepath.Join(dir, "config."+format)
+		t.NoError(manager.WriteConfigFile(f, ""))
+
+		loaded := t.newWriteManager()
+		t.NoError(loaded.SetViperConfigFile(f))
+		_, err := loaded.Merge()
+		t.NoError(err)
+
+		var level string
+		t.NoError(loaded.GetValue("log.level", &level))
+		t.Equal("error", level, format)
+
+		var file string
+		t.NoError(loaded.GetValue("log.file", &file))
+		t.Equal("naru.log", file, format)
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	t.NoError(err)
+	t.Equal(3, len(files))
+}
+
+func (t *testManager) TestWriteChangedConfigFile() {
+	dir, err := ioutil.TempDir("", "cvc")
+	t.NoError(err)
+	defer os.RemoveAll(dir)
+
+	manager := t.newWriteManager()
+	t.NoError(manager.SetValue("log.level", "error"))
+
+	f := filepath.Join(dir, "config")
+	t.NoError(manager.WriteChangedConfigFile(f, "yaml"))
+
+	b, err := ioutil.ReadFile(f)
+	t.NoError(err)
+	t.Equal("naru:\n  log:\n    level: error\n", string(b))
+}
+
+func (t *testManager) TestWriteConfigFileKeepMode() {
+	dir, err := ioutil.TempDir("", "cvc")
+	t.NoError(err)
+	defer os.RemoveAll(dir)
+
+	f := filepath.Join(dir, "config.yml")
+	t.NoError(ioutil.WriteFile(f, []byte("naru:\n"), 0600))
+
+	manager := t.newWriteManager()
+	t.NoError(manager.WriteConfigFile(f, ""))
+
+	fi, err := os.Stat(f)
+	t.NoError(err)
+	t.Equal(os.FileMode(0600), fi.Mode())
+
+	b, err := ioutil.ReadFile(f)
+	t.NoError(err)
+	t.Contains(string(b), "t: 1s\n")
+}
+
+func (t *testManager) TestWriteConfigFileUnknownFormat() {
+	manager := t.newWriteManager()
+	t.Error(manager.WriteConfigFile(filepath.Join(os.TempDir(), "config.conf"), ""))
+}
+
+func (t *testManager) TestViperString() {
+	manager := t.newWriteManager()
+
+	s, err := manager.ViperString("yml")
+	t.NoError(err)
+	t.Contains(s, "naru:\n")
+	t.Contains(s, "  a: 1\n")
+
+	s, err = manager.ViperString("props")
+	t.NoError(err)
+	t.Contains(s, "naru.log.file = naru.log\n")
+}
+
+type testConfigAliasLog struct {
+	BaseGroup
+
+	Path string `alias:"log.file,file-name"`
+}
+
+type testConfigAlias struct {
+	BaseGroup
+
+	Log *testConfigAliasLog
+}
+
+func (t *testManager) newAliasManager(envs map[string]string, args ...string) *Manager {
+	config := &testConfigAlias{
+		Log: &testConfigAliasLog{Path: "naru.log"},
+	}
+
+	return newTestManager(t.T(), config, envs, append([]string{}, args...))
+}
+
+func (t *testManager) TestAliasNames() {
+	manager := t.newAliasManager(nil)
+
+	item, found := manager.Get("log.path")
+	t.True(found)
+	t.Equal([]string{"log.file", "log.file-name"}, item.Aliases())
+
+	for _, name := range []string{"log-path", "log-file", "log-file-name"} {
+		it, found := manager.ItemByFlag(name)
+		t.True(found, name)
+		t.Equal(item, it)
+	}
+
+	t.Equal("use --log-path instead", manager.FlagSet().Lookup("log-file").Deprecated)
+}
+
+func (t *testManager) TestAliasConfig() {
+	manager := t.newAliasManager(nil)
+	manager.SetViperConfig("yml", []byte(`
+naru:
+  log:
+    file: /old.log
+`))
+	key, err := manager.Merge()
+	t.Empty(key)
+	t.NoError(err)
+	t.Equal("/old.log", MustGet[string](manager, "log.path"))
+	t.Equal([]string{"'naru.log.file' is deprecated; use 'naru.log.path'"}, manager.Snapshot().Deprecated())
+
+	manager = t.newAliasManager(nil)
+	manager.SetViperConfig("yml", []byte(`
+naru:
+  log:
+    file: /old.log
+    file-name: /other.log
+`))
+	key, err = manager.Merge()
+	t.Equal("naru.log.file-name", key)
+	t.Error(err)
+
+	var serr *SourceError
+	t.True(errors.As(err, &serr))
+	t.Equal(SourceConfig, serr.Source)
+
+	manager = t.newAliasManager(nil)
+	manager.SetViperConfig("yml", []byte(`
+naru:
+  log:
+    file-name: /old.log
+    path: /old.log
+`))
+	_, err = manager.Merge()
+	t.NoError(err)
+	t.Equal("/old.log", MustGet[string](manager, "log.path"))
+
+	manager = t.newAliasManager(nil)
+	manager.SetViperConfig("yml", []byte(`
+naru:
+  log:
+    file: /old.log
+`))
+	manager.SetViperConfig("yml", []byte(`
+naru:
+  log:
+    path: /new.log
+`))
+	key, err = manager.Merge()
+	t.Equal("naru.log.file", key)
+	t.Error(err)
+}
+
+func (t *testManager) TestAliasEnv() {
+	manager := t.newAliasManager(map[string]string{
+		"NARU_LOG_FILE": "/old.log",
+	})
+
+	_, err := manager.Merge()
+	t.NoError(err)
+	t.Equal("/old.log", MustGet[string](manager, "log.path"))
+	t.Equal([]string{"'NARU_LOG_FILE' is deprecated; use 'NARU_LOG_PATH'"}, manager.Snapshot().Deprecated())
+
+	manager = t.newAliasManager(map[string]string{
+		"NARU_LOG_FILE":      "/old.log",
+		"NARU_LOG_FILE_NAME": "/other.log",
+	})
+
+	key, err := manager.Merge()
+	t.Equal("NARU_LOG_FILE_NAME", key)
+	t.Error(err)
+
+	manager = t.newAliasManager(map[string]string{
+		"NARU_LOG_FILE": "/old.log",
+		"NARU_LOG_PATH": "/new.log",
+	})
+
+	key, err = manager.Merge()
+	t.Equal("NARU_LOG_FILE", key)
+	t.Error(err)
+}
+
+func (t *testManager) TestAliasFlag() {
+	manager := t.newAliasManager(nil, "--log-file", "/old.log")
+
+	_, err := manager.Merge()
+	t.NoError(err)
+	t.Equal("/old.log", MustGet[string](manager, "log.path"))
+	t.Equal([]string{"'--log-file' is deprecated; use '--log-path'"}, manager.Snapshot().Deprecated())
+
+	manager = t.newAliasManager(nil, "--log-file", "/old.log", "--log-file-name", "/other.log")
+
+	key, err := manager.Merge()
+	t.Equal("log-file", key)
+	t.Error(err)
+
+	manager = t.newAliasManager(nil, "--log-file", "/old.log", "--log-path", "/old.log")
+
+	_, err = manager.Merge()
+	t.NoError(err)
+	t.Equal("/old.log", MustGet[string](manager, "log.path"))
+
+	manager = t.newAliasManager(nil, "--log-file", "/old.log", "--log-path", "/new.log")
+
+	key, err = manager.Merge()
+	t.Equal("log-file", key)
+	t.Error(err)
+}
+
+func (t *testManager) TestViperMultipleConfigKeys() {
+	config := &testConfig{
+		A: 1,
+		B: "2",
+	}
+
+	manager := newTestManager(t.T(), config, nil, nil)
+	manager.SetViperConfig("yml", []byte(`
+naru:
+  a: "10"
+  b: "20"
+`))
+	manager.SetViperConfig("json", []byte(`{"naru": {"a": "3"}}`))
+	_, err := manager.Merge()
+	t.NoError(err)
+
+	t.Equal(3, config.A)
+	t.Equal("20", config.B)
+}
+
+func (t *testManager) TestViperUnknownKey() {
+	manager := t.newAliasManager(nil)
+	manager.SetViperConfig("yml", []byte(`
+naru:
+  log:
+    level: debug
+`))
+	key, err := manager.Merge()
+	t.Equal("naru.log.level", key)
+	t.Error(err)
+}
+
+type testConfigMigrationLog struct {
+	BaseGroup
+
+	File  string
+	Level string
+}
+
+type testConfigMigration struct {
+	BaseGroup
+
+	Timeout time.Duration
+	Log     *testConfigMigrationLog
+}
+
+func (t *testManager) newMigrationManager() (*testConfigMigration, *Manager) {
+	config := &testConfigMigration{
+		Log: &testConfigMigrationLog{},
+	}
+
+	manager := newTestManager(t.T(), config, nil, nil)
+
+	// version 0: `log` is the file path and `timeout` is seconds
+	t.NoError(manager.RegisterMigration(0, func(c map[string]interface{}) error {
+		if file, found := c["log"]; found {
+			c["log"] = map[string]interface{}{"file": file}
+		}
+		return nil
+	}))
+	t.NoError(manager.RegisterMigration(1, func(c map[string]interface{}) error {
+		if timeout, found := c["timeout"]; found {
+			c["timeout"] = fmt.Sprintf("%vs", timeout)
+		}
+		return nil
+	}))
+
+	return config, manager
+}
+
+func (t *testManager) TestMigration() {
+	config, manager := t.newMigrationManager()
+	t.Equal(2, manager.ConfigVersion())
+
+	manager.SetViperConfig("yml", []byte(`
+naru:
+  log: /naru.log
+  timeout: 3
+`))
+	key, err := manager.Merge()
+	t.Empty(key)
+	t.NoError(err)
+
+	t.Equal("/naru.log", config.Log.File)
+	t.Equal(time.Second*3, config.Timeout)
+	t.Equal(2, manager.Viper().GetInt("naru.version"))
+}
+
+func (t *testManager) TestMigrationFromVersion() {
+	config, manager := t.newMigrationManager()
+
+	manager.SetViperConfig("yml", []byte(`
+naru:
+  version: 1
+  log:
+    file: /naru.log
+  timeout: 3
+`))
+	_, err := manager.Merge()
+	t.NoError(err)
+
+	t.Equal("/naru.log", config.Log.File)
+	t.Equal(time.Second*3, config.Timeout)
+}
+
+func (t *testManager) TestMigrationLatest() {
+	config, manager := t.newMigrationManager()
+
+	manager.SetViperConfig("yml", []byte(`
+naru:
+  version: 2
+  timeout: 3m
+`))
+	_, err := manager.Merge()
+	t.NoError(err)
+	t.Equal(time.Minute*3, config.Timeout)
+}
+
+func (t *testManager) TestMigrationUnknownVersion() {
+	_, manager := t.newMigrationManager()
+
+	manager.SetViperConfig("yml", []byte(`
+naru:
+  version: 3
+`))
+	key, err := manager.Merge()
+	t.Equal("naru.version", key)
+	t.Error(err)
+
+	t.Error(manager.RegisterMigration(1, func(map[string]interface{}) error { return nil }))
+}
+
+func (t *testManager) TestMigrationError() {
+	_, manager := t.newMigrationManager()
+	t.NoError(manager.RegisterMigration(2, func(map[string]interface{}) error {
+		return io.EOF
+	}))
+
+	manager.SetViperConfig("yml", []byte(`
+naru:
+  version: 2
+`))
+	_, err := manager.Merge()
+	t.True(errors.Is(err, io.EOF))
+
+	var e *SourceError
+	t.True(errors.As(err, &e))
+	t.Equal(ConfigVersionKey, e.Key)
+	t.Equal(2, e.Input)
+}
+
+type testConfigDefaultLevel struct {
+	Name string
+}
+
+type testConfigDefaultLog struct {
+	BaseGroup
+
+	File  string                 `default:"naru.log"`
+	Level testConfigDefaultLevel `default:"debug"`
+}
+
+func (l *testConfigDefaultLog) ParseLevel(input string) (testConfigDefaultLevel, error) {
+	return testConfigDefaultLevel{Name: input}, nil
+}
+
+type testConfigDefault struct {
+	BaseGroup
+
+	A       int           `default:"10"`
+	B       string        `default:"showme"`
+	Timeout time.Duration `default:"3s"`
+	Verbose bool          `default:"true"`
+	Log     *testConfigDefaultLog
+}
+
+func (t *testManager) newDefaultManager(config *testConfigDefault) *Manager {
+	return newTestManager(t.T(), config, nil, nil)
+}
+
+func (t *testManager) TestDefaultTag() {
+	config := &testConfigDefault{B: "findme"}
+	manager := t.newDefaultManager(config)
+
+	t.Equal(10, config.A)
+	t.Equal("findme", config.B)
+	t.Equal(time.Second*3, config.Timeout)
+	t.True(config.Verbose)
+	t.Equal("naru.log", config.Log.File)
+	t.Equal("debug", config.Log.Level.Name)
+
+	t.Equal("10", manager.FlagSet().Lookup("a").DefValue)
+	t.Equal("findme", manager.FlagSet().Lookup("b").DefValue)
+	t.Equal("3s", manager.FlagSet().Lookup("timeout").DefValue)
+	t.Equal("naru.log", manager.FlagSet().Lookup("log-file").DefValue)
+
+	for _, e := range manager.References() {
+		if e.Key == "timeout" {
+			t.Equal("3s", e.Default)
+		}
+	}
+}
+
+type testConfigInvalidDefault struct {
+	A       int `default:"10"`
+	Invalid int `default:"ten"`
+}
+
+func (t *testManager) TestDefaultTagInvalid() {
+	config := &testConfigInvalidDefault{}
+
+	manager := newTestManager(t.T(), config, nil, []string{})
+
+	t.Equal(10, config.A)
+	t.Equal(0, config.Invalid)
+
+	key, err := manager.Merge()
+	t.Equal("invalid", key)
+	t.True(errors.Is(err, ErrorParse))
+
+	var e *ParseError
+	t.True(errors.As(err, &e))
+	t.Equal(SourceDefault, e.Source)
+	t.Equal("ten", e.Input)
+
+	errs := manager.Lint()
+	t.Equal(1, len(errs))
+	t.True(errors.As(errs[0], &e))
+	t.Equal("invalid", e.Key)
+}
+
+func (t *testManager) TestDefaultTagMerge() {
+	config := &testConfigDefault{}
+	manager := t.newDefaultManager(config)
+
+	executeTestManager(t.T(), manager, "--a", "20")
+
+	_, err := manager.Merge()
+	t.NoError(err)
+	t.Equal(20, config.A)
+	t.Equal("showme", config.B)
+
+	s, err := manager.ViperString("yml")
+	t.NoError(err)
+	t.Contains(s, "  b: showme\n")
+}
+
+func (t *testManager) newConfigFlagsManager(config *testConfig, args ...string) *Manager {
+	manager := newTestManager(t.T(), config, nil, args, WithConfigFlags())
+
+	return manager
+}
+
+func (t *testManager) TestConfigFlagsStdin() {
+	config := &testConfig{A: 1, B: "b"}
+	manager := t.newConfigFlagsManager(config, "--config", "-", "--config-format", "yml", "--b", "c")
+	manager.SetStdin(strings.NewReader(`
+naru:
+  a: "10"
+`))
+
+	_, err := manager.Merge()
+	t.NoError(err)
+	t.Equal(10, config.A)
+	t.Equal("c", config.B)
+
+	// the config files are loaded once
+	_, err = manager.Merge()
+	t.NoError(err)
+	t.Equal(1, len(manager.viperConfigs))
+}
+
+func (t *testManager) TestConfigFlagsFile() {
+	dir, err := ioutil.TempDir("", "cvc")
+	t.NoError(err)
+	defer os.RemoveAll(dir)
+
+	f := filepath.Join(dir, "naru.conf")
+	t.NoError(ioutil.WriteFile(f, []byte(`{"naru": {"a": "10"}}`), 0644))
+
+	config := &testConfig{A: 1, B: "b"}
+	manager := t.newConfigFlagsManager(config, "--config", f, "--config-format", "json")
+
+	_, err = manager.Merge()
+	t.NoError(err)
+	t.Equal(10, config.A)
+}
+
+func (t *testManager) TestConfigFlagsStdinWithoutFormat() {
+	config := &testConfig{A: 1, B: "b"}
+	manager := t.newConfigFlagsManager(config, "--config", "-")
+	manager.SetStdin(strings.NewReader("naru:\n  a: \"10\"\n"))
+
+	_, err := manager.Merge()
+	t.NoError(err)
+	t.Equal(10, config.A)
+
+	manager = t.newConfigFlagsManager(&testConfig{A: 1, B: "b"}, "--config", "-")
+	manager.SetStdin(strings.NewReader("naru"))
+
+	key, err := manager.Merge()
+	t.Equal("config", key)
+	t.True(errors.Is(err, ErrorSource))
+}
+
+func (t *testManager) TestConfigFlagsFailed() {
+	dir, err := ioutil.TempDir("", "cvc")
+	t.NoError(err)
+	defer os.RemoveAll(dir)
+
+	a := filepath.Join(dir, "a.yml")
+	t.NoError(ioutil.WriteFile(a, []byte("naru:\n  a: \"10\"\n"), 0644))
+
+	manager := t.newConfigFlagsManager(&testConfig{A: 1, B: "b"}, "--config", a, "--config", filepath.Join(dir, "b.yml"))
+
+	// the failed config files are not skipped by the next merge
+	for i := 0; i < 2; i++ {
+		key, err := manager.Merge()
+		t.Equal("config", key)
+		t.True(errors.Is(err, ErrorSource))
+	}
+	t.Empty(manager.viperConfigs)
+}
+
+type testConfigFlagsConflictConfig struct {
+	Config string
+}
+
+func (t *testManager) TestConfigFlagsConflict() {
+	config := &testConfigFlagsConflictConfig{}
+
+	manager := newTestManager(t.T(), config, nil, []string{"--config", "a"}, WithConfigFlags())
+
+	_, err := manager.Merge()
+	var e *SourceError
+	t.True(errors.As(err, &e))
+	t.Equal(SourceFlag, e.Source)
+	t.EqualError(e.Err, "flag 'config' of config is already defined; set the other name by WithConfigFlagNames")
+
+	manager = newTestManager(
+		t.T(), config, nil, []string{"--config-file", "-", "--config-file-format", "yml"},
+		WithConfigFlagNames("config-file", "config-file-format"),
+	)
+	manager.SetStdin(strings.NewReader("naru:\n  config: b\n"))
+
+	_, err = manager.Merge()
+	t.NoError(err)
+	t.Equal("b", config.Config)
+}
+
+func (t *testManager) TestViperConfigFS() {
+	config := &testConfig{A: 1, B: "b"}
+
+	manager := newTestManager(t.T(), config, nil, nil)
+
+	fsys := fstest.MapFS{
+		"config/a.yml":  {Data: []byte("naru:\n  a: \"10\"\n")},
+		"config/b.toml": {Data: []byte("[naru]\nb = \"c\"\n")},
+		"config/c.conf": {Data: []byte("naru")},
+	}
+
+	t.NoError(manager.SetViperConfigFS(fsys, "config/a.yml", "config/b.toml"))
+	t.Error(manager.SetViperConfigFS(fsys, "config/c.conf"))
+
+	err := manager.SetViperConfigFS(fsys, "config/d.yml")
+	var e *SourceError
+	t.True(errors.As(err, &e))
+	t.Equal("config/d.yml", e.Input)
+
+	_, err = manager.Merge()
+	t.NoError(err)
+	t.Equal(10, config.A)
+	t.Equal("c", config.B)
+}
+
 func TestManager(t *testing.T) {
 	suite.Run(t, new(testManager))
 }
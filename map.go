@@ -0,0 +1,178 @@
+package cvc
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cast"
+)
+
+// SetMapTemplate sets the template of the new entries of the map of groups;
+// the new entry is the copy of template, and then the defaults are applied.
+func (m *Manager) SetMapTemplate(key string, template interface{}) error {
+	m.Lock()
+	defer m.Unlock()
+
+	item, found := m.m[key]
+	if !found {
+		return ErrorKeyNotFound.Clone().Set("key", key)
+	}
+	if !item.IsMap {
+		return fmt.Errorf("'%s' is not map of groups", key)
+	}
+
+	v := reflect.ValueOf(template)
+	if !v.IsValid() || !v.Type().AssignableTo(item.Value.Type().Elem()) || v.IsNil() {
+		return ErrorNotAssignable.Clone().Set("key", key).Set("value", fmt.Sprintf("%T", template))
+	}
+
+	if m.templates == nil {
+		m.templates = map[string]reflect.Value{}
+	}
+	m.templates[key] = v
+
+	return nil
+}
+
+// addMapEntries adds the new entries of the keys to the map of groups.
+func (m *Manager) addMapEntries(group *Item, keys []string) {
+	if group.Value.IsNil() {
+		group.Value.Set(reflect.MakeMap(group.Value.Type()))
+	}
+
+	for _, k := range keys {
+		ev := reflect.New(group.Value.Type().Elem().Elem())
+		if t, found := m.templates[group.FullName()]; found {
+			ev = deepCopy(t)
+		}
+
+		group.Value.SetMapIndex(reflect.ValueOf(k).Convert(group.Value.Type().Key()), ev)
+	}
+
+	m.reparseElements(group)
+}
+
+// addMapEntriesFromConfig adds the entries to the maps of groups by the keys
+// of the config settings.
+func (m *Manager) addMapEntriesFromConfig(settings map[string]interface{}) {
+	for {
+		var groups []*Item
+		for _, item := range m.m {
+			if item.IsMap {
+				groups = append(groups, item)
+			}
+		}
+		sort.Slice(groups, func(i, j int) bool {
+			return groups[i].FullName() < groups[j].FullName()
+		})
+
+		var added bool
+		for _, group := range groups {
+			sub, found := configValueByKey(settings, strings.Split(group.FullName(), "."))
+			if !found {
+				continue
+			}
+
+			var keys []string
+			for k := range cast.ToStringMap(sub) {
+				if _, _, found := m.configItem(group.FullName() + "." + k); !found {
+					keys = append(keys, k)
+				}
+			}
+
+			if len(keys) > 0 {
+				sort.Strings(keys)
+				m.addMapEntries(group, keys)
+				added = true
+				break
+			}
+		}
+
+		if !added {
+			return
+		}
+	}
+}
+
+// mapKeysFromEnv finds the new keys of the map of groups from the envs; the
+// key is between the env name of the map and the env name of the item in the
+// entry, like `PRIMARY` of `NARU_DATABASES_PRIMARY_DSN`.
+func (m *Manager) mapKeysFromEnv(group *Item) []string {
+	prefix := m.EnvName(group) + "_"
+
+	ev := reflect.New(group.Value.Type().Elem().Elem())
+	entry, items := parseMapElement(m.c, group, "x", ev)
+	entryPrefix := m.EnvName(entry) + "_"
+
+	var suffixes []string
+	for _, item := range items {
+		if item.IsGroup {
+			continue
+		}
+
+		if env := m.EnvName(item); strings.HasPrefix(env, entryPrefix) {
+			suffixes = append(suffixes, "_"+env[len(entryPrefix):])
+		}
+	}
+
+	found := map[string]bool{}
+	for _, e := range m.environFunc() {
+		env := strings.SplitN(e, "=", 2)[0]
+		if !strings.HasPrefix(env, prefix) {
+			continue
+		}
+
+		for _, suffix := range suffixes {
+			if !strings.HasSuffix(env, suffix) || len(env) <= len(prefix)+len(suffix) {
+				continue
+			}
+			if _, ok := m.envLookupFunc(env); !ok {
+				continue
+			}
+
+			k := strings.ToLower(env[len(prefix) : len(env)-len(suffix)])
+			if _, _, exists := m.configItem(group.FullName() + "." + k); !exists {
+				found[k] = true
+			}
+		}
+	}
+
+	var keys []string
+	for k := range found {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// configValueByKey finds the value of the config settings by the names of
+// key; the index of the sequence is also the name.
+func configValueByKey(settings map[string]interface{}, names []string) (interface{}, bool) {
+	var v interface{} = settings
+	for _, n := range names {
+		switch t := v.(type) {
+		case []interface{}:
+			i, err := strconv.Atoi(n)
+			if err != nil || i < 0 || i >= len(t) {
+				return nil, false
+			}
+			v = t[i]
+		default:
+			sm, err := cast.ToStringMapE(v)
+			if err != nil {
+				return nil, false
+			}
+
+			var found bool
+			if v, found = sm[strings.ToLower(n)]; !found {
+				return nil, false
+			}
+		}
+	}
+
+	return v, true
+}
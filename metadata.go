@@ -0,0 +1,125 @@
+package cvc
+
+import (
+	"reflect"
+	"sync"
+)
+
+// typeMeta is the reflection metadata of the config type; it is computed once
+// and shared by the managers.
+type typeMeta struct {
+	methods map[string]reflect.Method
+	fields  []fieldMeta
+}
+
+type fieldMeta struct {
+	index      int
+	field      reflect.StructField
+	isGroup    bool
+	isEmbedded bool
+	isList     bool
+	isMap      bool
+	isUnion    bool
+}
+
+var typeMetas sync.Map
+
+func metaOf(t reflect.Type) *typeMeta {
+	if m, found := typeMetas.Load(t); found {
+		return m.(*typeMeta)
+	}
+
+	m, _ := typeMetas.LoadOrStore(t, newTypeMeta(t))
+	return m.(*typeMeta)
+}
+
+func newTypeMeta(t reflect.Type) *typeMeta {
+	m := &typeMeta{methods: map[string]reflect.Method{}}
+	for i := 0; i < t.NumMethod(); i++ {
+		method := t.Method(i)
+		m.methods[method.Name] = method
+	}
+
+	st := t
+	if st.Kind() == reflect.Ptr {
+		st = st.Elem()
+	}
+	if st.Kind() != reflect.Struct {
+		return m
+	}
+
+	for i := 0; i < st.NumField(); i++ {
+		ft := st.Field(i)
+		if ft.Type == reflect.TypeOf(BaseGroup{}) {
+			continue
+		}
+
+		m.fields = append(m.fields, fieldMeta{
+			index:      i,
+			field:      ft,
+			isGroup:    isGroupField(ft),
+			isList:     isListField(ft),
+			isMap:      isMapField(ft),
+			isUnion:    isUnionField(ft),
+			isEmbedded: ft.Anonymous && isStructType(ft.Type) && ft.Tag.Get("group") != "true",
+		})
+	}
+
+	return m
+}
+
+// isGroupField checks whether the field is the group; the struct, which does
+// not embed BaseGroup, is the group with the `group:"true"` tag.
+func isGroupField(ft reflect.StructField) bool {
+	if ft.Type.Implements(groupType) {
+		return true
+	}
+
+	return ft.Tag.Get("group") == "true" && isStructType(ft.Type)
+}
+
+// isUnionField checks whether the field is the union; the field of the
+// interface type is the union with the `union:"true"` tag, and the other
+// interface fields, like `io.Writer`, are the plain values.
+func isUnionField(ft reflect.StructField) bool {
+	return ft.Type.Kind() == reflect.Interface && ft.Type.NumMethod() > 0 && ft.Tag.Get("union") == "true"
+}
+
+func isStructType(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	return t.Kind() == reflect.Struct
+}
+
+// isListField checks whether the field is the slice of groups.
+func isListField(ft reflect.StructField) bool {
+	if ft.Type.Kind() != reflect.Slice {
+		return false
+	}
+
+	return isGroupElem(ft, ft.Type.Elem())
+}
+
+// isMapField checks whether the field is the map of groups, like
+// `map[string]*DBConfig`; the value of map must be pointer to be set.
+func isMapField(ft reflect.StructField) bool {
+	if ft.Type.Kind() != reflect.Map || ft.Type.Key().Kind() != reflect.String {
+		return false
+	}
+
+	return ft.Type.Elem().Kind() == reflect.Ptr && isGroupElem(ft, ft.Type.Elem())
+}
+
+func isGroupElem(ft reflect.StructField, et reflect.Type) bool {
+	if et.Kind() != reflect.Ptr {
+		et = reflect.PtrTo(et)
+	}
+
+	if et.Implements(groupType) {
+		return true
+	}
+
+	return ft.Tag.Get("group") == "true" && isStructType(et)
+}
@@ -0,0 +1,154 @@
+package cvc
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type testBenchGroupConfig struct {
+	BaseGroup
+
+	Name        string
+	Port        int
+	Timeout     time.Duration
+	Enabled     bool
+	Retries     int
+	Path        string
+	MaxSize     int64
+	Ratio       float64
+	Description string
+	Mode        string
+}
+
+func (g *testBenchGroupConfig) ParsePort(i string) (int, error) {
+	n, err := strconv.ParseInt(i, 10, 64)
+	return int(n), err
+}
+
+func (g *testBenchGroupConfig) ValidatePort() error {
+	return nil
+}
+
+type testBenchConfig struct {
+	BaseGroup
+
+	Home    string
+	Verbose bool
+	Network *testBenchGroupConfig
+	Storage *testBenchGroupConfig
+	Log     *testBenchGroupConfig
+	Metrics *testBenchGroupConfig
+	Cache   *testBenchGroupConfig
+}
+
+func newTestBenchManager(t require.TestingT) (*testBenchConfig, *Manager) {
+	config := &testBenchConfig{}
+
+	return config, newTestManager(t, config, nil, nil)
+}
+
+type testMetadata struct {
+	suite.Suite
+}
+
+func (t *testMetadata) TestShared() {
+	newTestBenchManager(t.T())
+
+	a := metaOf(reflect.TypeOf(&testBenchGroupConfig{}))
+	b := metaOf(reflect.TypeOf(&testBenchGroupConfig{}))
+	t.True(a == b)
+
+	_, found := a.methods["ParsePort"]
+	t.True(found)
+	_, found = a.methods["ThisIsGroup"]
+	t.True(found)
+
+	var names []string
+	for _, f := range metaOf(reflect.TypeOf(&testBenchConfig{})).fields {
+		names = append(names, f.field.Name)
+	}
+	t.Equal([]string{"Home", "Verbose", "Network", "Storage", "Log", "Metrics", "Cache"}, names)
+}
+
+func (t *testMetadata) TestNames() {
+	_, manager := newTestBenchManager(t.T())
+
+	item := manager.Map()["network.max-size"]
+	t.NotNil(item)
+	t.Equal("network.max-size", item.FullName())
+	t.Equal([]string{"Network"}, item.prefixes())
+	t.Equal("NARU_NETWORK_MAX_SIZE", manager.EnvName(item))
+
+	t.Equal("Int64Var", item.flagType)
+	t.Equal("StringVar", manager.Map()["network.port"].flagType) // by ParsePort
+	t.True(item.envCached)
+	t.Equal("NARU_NETWORK_MAX_SIZE", item.envName)
+}
+
+func (t *testMetadata) TestEnvNameOfSubcommand() {
+	manager := newTestManager(t.T(), &testBenchConfig{}, nil, nil, WithoutRootEnvGroup())
+
+	item := manager.Map()["network.max-size"]
+	t.Equal("NETWORK_MAX_SIZE", item.envName)
+
+	// the env names are cached again, after the command is added to the parent
+	parent := &cobra.Command{Use: "main"}
+	parent.AddCommand(manager.Cobra())
+	t.Equal("NARU_NETWORK_MAX_SIZE", manager.EnvName(item))
+
+	_, err := manager.Merge()
+	t.NoError(err)
+	t.Equal("NARU_NETWORK_MAX_SIZE", item.envName)
+}
+
+func TestMetadata(t *testing.T) {
+	suite.Run(t, new(testMetadata))
+}
+
+func BenchmarkNormalizeVar(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		NormalizeVar("Network.MaxSize", ".")
+	}
+}
+
+func BenchmarkGetMethodByName(b *testing.B) {
+	config := &testBenchGroupConfig{}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		GetMethodByName(config, "ParsePort", 1, 2)
+	}
+}
+
+func BenchmarkNewManager(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		newTestBenchManager(b)
+	}
+}
+
+func BenchmarkEnvName(b *testing.B) {
+	_, manager := newTestBenchManager(b)
+	item := manager.Map()["network.max-size"]
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		manager.EnvName(item)
+	}
+}
+
+func BenchmarkMerge(b *testing.B) {
+	_, manager := newTestBenchManager(b)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := manager.Merge(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package cvc
+
+import (
+	"strings"
+)
+
+// NamingStrategy derives the names of item from the names of the groups and
+// the item; the name is the field name or the `flag` tag. ConfigKey must join
+// the names with '.'.
+type NamingStrategy interface {
+	ConfigKey(names []string) string
+	FlagName(names []string) string
+	EnvName(prefix string, names []string) string
+}
+
+// DefaultNaming uses kebab-case for config keys and flags, and upper snake
+// case for env names, like `log.file-name`, `--log-file-name` and
+// `NARU_LOG_FILE_NAME`.
+type DefaultNaming struct{}
+
+func (n DefaultNaming) ConfigKey(names []string) string {
+	return NormalizeVar(strings.Join(names, "."), ".")
+}
+
+func (n DefaultNaming) FlagName(names []string) string {
+	return strings.Replace(n.ConfigKey(names), ".", "-", -1)
+}
+
+func (n DefaultNaming) EnvName(prefix string, names []string) string {
+	return envName(prefix, names)
+}
+
+// SnakeCaseNaming uses snake_case for config keys, like `log.file_name`.
+type SnakeCaseNaming struct {
+	DefaultNaming
+}
+
+func (n SnakeCaseNaming) ConfigKey(names []string) string {
+	return strings.Replace(n.DefaultNaming.ConfigKey(names), "-", "_", -1)
+}
+
+// CamelCaseNaming uses camelCase for config keys, like `log.fileName`.
+type CamelCaseNaming struct {
+	DefaultNaming
+}
+
+func (n CamelCaseNaming) ConfigKey(names []string) string {
+	var keys []string
+	for _, k := range strings.Split(n.DefaultNaming.ConfigKey(names), ".") {
+		words := strings.Split(k, "-")
+		for i := 1; i < len(words); i++ {
+			if len(words[i]) > 0 {
+				words[i] = strings.ToUpper(words[i][:1]) + words[i][1:]
+			}
+		}
+		keys = append(keys, strings.Join(words, ""))
+	}
+
+	return strings.Join(keys, ".")
+}
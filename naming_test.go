@@ -0,0 +1,157 @@
+package cvc
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/suite"
+)
+
+type testNamingLogConfig struct {
+	BaseGroup
+
+	FileName string
+}
+
+type testNamingConfig struct {
+	BaseGroup
+
+	ListenPort int
+	Log        *testNamingLogConfig
+}
+
+type testUpperNaming struct {
+	DefaultNaming
+}
+
+func (n testUpperNaming) FlagName(names []string) string {
+	return strings.ToUpper(n.DefaultNaming.FlagName(names))
+}
+
+type testNaming struct {
+	suite.Suite
+}
+
+func (t *testNaming) newManager(options ...Option) (*testNamingConfig, *Manager) {
+	config := &testNamingConfig{
+		Log: &testNamingLogConfig{},
+	}
+
+	return config, newTestManager(t.T(), config, nil, nil, options...)
+}
+
+func (t *testNaming) keys(manager *Manager) []string {
+	var keys []string
+	for k := range manager.Map() {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+func (t *testNaming) flags(manager *Manager) []string {
+	var flags []string
+	manager.FlagSet().VisitAll(func(f *pflag.Flag) {
+		flags = append(flags, f.Name)
+	})
+	sort.Strings(flags)
+
+	return flags
+}
+
+func (t *testNaming) TestDefault() {
+	names := []string{"Log", "FileName"}
+	t.Equal("log.file-name", DefaultNaming{}.ConfigKey(names))
+	t.Equal("log-file-name", DefaultNaming{}.FlagName(names))
+	t.Equal("NARU_LOG_FILE_NAME", DefaultNaming{}.EnvName("naru", names))
+	t.Equal("log.file_name", SnakeCaseNaming{}.ConfigKey(names))
+	t.Equal("log-file-name", SnakeCaseNaming{}.FlagName(names))
+	t.Equal("log.fileName", CamelCaseNaming{}.ConfigKey(names))
+}
+
+func (t *testNaming) TestSnakeCase() {
+	config, manager := t.newManager(WithNamingStrategy(SnakeCaseNaming{}))
+	t.Equal([]string{"listen_port", "log", "log.file_name"}, t.keys(manager))
+	t.Equal([]string{"listen-port", "log-file-name"}, t.flags(manager))
+	t.Equal([]string{"NARU_LISTEN_PORT", "NARU_LOG_FILE_NAME"}, manager.Envs())
+
+	manager.SetViperConfig("yml", []byte(`
+naru:
+  listen_port: 80
+  log:
+    file_name: naru.log
+`))
+	_, err := manager.Merge()
+	t.NoError(err)
+	t.Equal(80, config.ListenPort)
+	t.Equal("naru.log", config.Log.FileName)
+}
+
+func (t *testNaming) TestCamelCase() {
+	config, manager := t.newManager(WithNamingStrategy(CamelCaseNaming{}))
+	t.Equal([]string{"listenPort", "log", "log.fileName"}, t.keys(manager))
+
+	manager.SetViperConfig("json", []byte(`{"naru": {"listenPort": 80, "log": {"fileName": "naru.log"}}}`))
+	_, err := manager.Merge()
+	t.NoError(err)
+	t.Equal(80, config.ListenPort)
+	t.Equal("naru.log", config.Log.FileName)
+
+	_, found := manager.Snapshot().Get("log.fileName")
+	t.True(found)
+}
+
+type testNamingConflictConfig struct {
+	BaseGroup
+
+	FileName string
+	Filename string
+}
+
+func (t *testNaming) TestCamelCaseConflict() {
+	config := &testNamingConflictConfig{}
+	manager := newTestManager(t.T(), config, nil, nil, WithNamingStrategy(CamelCaseNaming{}))
+
+	key, err := manager.Merge()
+	t.Equal("filename", key)
+	t.EqualError(err, "invalid config, 'filename': 'fileName' and 'filename' are the same key in config")
+
+	var serr *SourceError
+	t.True(errors.As(err, &serr))
+
+	errs := manager.Lint()
+	t.Equal(1, len(errs))
+	t.Equal(err, errs[0])
+}
+
+func (t *testNaming) TestNoPrefixEnv() {
+	config, manager := t.newManager(WithEnvPrefix(""), WithoutRootEnvGroup())
+	t.Equal([]string{"LISTEN_PORT", "LOG_FILE_NAME"}, manager.Envs())
+
+	manager.SetEnvLookupFunc(func(s string) (string, bool) {
+		if s == "LOG_FILE_NAME" {
+			return "naru.log", true
+		}
+		return "", false
+	})
+	_, err := manager.Merge()
+	t.NoError(err)
+	t.Equal("naru.log", config.Log.FileName)
+}
+
+func (t *testNaming) TestCustom() {
+	_, manager := t.newManager(WithNamingStrategy(testUpperNaming{}))
+	t.Equal([]string{"LISTEN-PORT", "LOG-FILE-NAME"}, t.flags(manager))
+
+	item, found := manager.ItemByFlag("LOG-FILE-NAME")
+	t.True(found)
+	t.Equal("log.file-name", item.FullName())
+}
+
+func TestNaming(t *testing.T) {
+	suite.Run(t, new(testNaming))
+}
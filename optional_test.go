@@ -0,0 +1,129 @@
+package cvc
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type testOptionalConfig struct {
+	BaseGroup
+	Port  *int
+	Debug *bool
+	Name  *string
+	Level *int `default:"3"`
+}
+
+type testOptional struct {
+	suite.Suite
+}
+
+func (t *testOptional) newManager(envs map[string]string, args ...string) (*testOptionalConfig, *Manager) {
+	config := &testOptionalConfig{}
+
+	return config, newTestManager(t.T(), config, envs, append([]string{}, args...))
+}
+
+func (t *testOptional) TestUnset() {
+	config, manager := t.newManager(nil)
+
+	_, err := manager.Merge()
+	t.NoError(err)
+
+	t.Nil(config.Port)
+	t.Nil(config.Debug)
+	t.Nil(config.Name)
+	t.Equal(3, *config.Level)
+
+	t.Equal("0", manager.FlagSet().Lookup("port").DefValue)
+	t.False(manager.Map()["port"].Changed())
+	t.NotContains(manager.ConfigString(), `"Port"`)
+	t.Contains(manager.ConfigString(), `"Level": 3`)
+}
+
+func (t *testOptional) TestSet() {
+	config, manager := t.newManager(map[string]string{"NARU_NAME": "naru"}, "--port", "0")
+	manager.SetViperConfig("yml", []byte(`
+naru:
+  debug: false
+`))
+
+	_, err := manager.Merge()
+	t.NoError(err)
+
+	t.Equal(0, *config.Port)
+	t.False(*config.Debug)
+	t.Equal("naru", *config.Name)
+	t.True(manager.Map()["port"].Changed())
+	t.Contains(manager.ConfigString(), `"Port": 0`)
+
+	v, found := manager.Snapshot().Get("debug")
+	t.True(found)
+	t.False(*v.(*bool))
+}
+
+func (t *testOptional) TestNull() {
+	config, manager := t.newManager(nil, "--port", "1")
+	t.NoError(manager.SetRaw("port", (*int)(nil)))
+	t.Nil(config.Port)
+
+	t.NoError(manager.SetValue("port", 3))
+	t.Equal(3, *config.Port)
+}
+
+type testOptionalHookConfig struct {
+	Port *int
+}
+
+func (t *testOptionalHookConfig) ParsePort(i string) (int, error) {
+	return strconv.Atoi(i)
+}
+
+func (t *testOptional) TestHook() {
+	config := &testOptionalHookConfig{}
+
+	manager := newTestManager(t.T(), config, nil, []string{"--port", "8080"})
+
+	_, err := manager.Merge()
+	t.NoError(err)
+	t.NotNil(config.Port)
+	t.Equal(8080, *config.Port)
+}
+
+func (t *testOptional) TestChangedDefault() {
+	_, manager := t.newManager(nil)
+
+	_, err := manager.Merge()
+	t.NoError(err)
+	t.False(manager.Map()["level"].Changed())
+
+	dir, err := ioutil.TempDir("", "cvc")
+	t.NoError(err)
+	defer os.RemoveAll(dir)
+
+	f := filepath.Join(dir, "config.yml")
+	t.NoError(manager.WriteChangedConfigFile(f, ""))
+
+	b, err := ioutil.ReadFile(f)
+	t.NoError(err)
+	t.NotContains(string(b), "level")
+
+	t.NoError(manager.SetValue("level", 4))
+	t.True(manager.Map()["level"].Changed())
+	t.NoError(manager.WriteChangedConfigFile(f, ""))
+
+	b, err = ioutil.ReadFile(f)
+	t.NoError(err)
+	t.Equal("naru:\n  level: 4\n", string(b))
+
+	t.NoError(manager.SetRaw("level", (*int)(nil)))
+	t.True(manager.Map()["level"].Changed())
+}
+
+func TestOptional(t *testing.T) {
+	suite.Run(t, new(testOptional))
+}
@@ -0,0 +1,235 @@
+package cvc
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+type ReferenceEntry struct {
+	Key         string
+	Group       string
+	Type        string
+	Default     string
+	Flag        string
+	Env         string
+	Help        string
+	Constraints []string
+}
+
+func (m *Manager) References() []ReferenceEntry {
+	m.RLock()
+	defer m.RUnlock()
+
+	var entries []ReferenceEntry
+	for _, item := range m.m {
+		if item.IsGroup || item.inCollection() {
+			continue
+		}
+
+		entries = append(entries, m.reference(item))
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Group != entries[j].Group {
+			return entries[i].Group < entries[j].Group
+		}
+		return entries[i].Key < entries[j].Key
+	})
+
+	return entries
+}
+
+func (m *Manager) reference(item *Item) ReferenceEntry {
+	entry := ReferenceEntry{
+		Key:  item.FullName(),
+		Type: strings.ToLower(strings.TrimSuffix(item.FlagType(), "Var")),
+		Env:  m.EnvName(item),
+		Help: item.Tag.Get("flag-help"),
+	}
+
+	if item.Group != nil {
+		entry.Group = item.Group.FullName()
+	}
+
+	if item.Default != nil {
+		entry.Default = fmt.Sprintf("%v", item.Default)
+	}
+
+	if item.EnableFlag() && len(item.FlagName()) > 0 {
+		entry.Flag = "--" + item.FlagName()
+	}
+
+	for _, n := range hookMethods(item, "Parse", 1, 2) {
+		entry.Constraints = append(entry.Constraints, "parsed by "+n)
+	}
+	for _, n := range validateMethods(item) {
+		entry.Constraints = append(entry.Constraints, "validated by "+n)
+	}
+	entry.Constraints = append(entry.Constraints, validateHelp(item)...)
+
+	return entry
+}
+
+// hookMethods returns the names of the hook methods of the item, like
+// `LogConfig.ValidateLevel`.
+func hookMethods(item *Item, hook string, numIn, numOut int) []string {
+	var names []string
+	add := func(body reflect.Value, name string) {
+		if fn, found := GetMethodByName(body.Interface(), name, numIn, numOut); !found || fn.Empty() {
+			return
+		}
+
+		t := body.Type()
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		names = append(names, t.Name()+"."+name)
+	}
+
+	if !item.IsUnion {
+		add(methodBody(item), hook)
+	}
+	if item.Group != nil {
+		add(methodBody(item.Group), hook+item.FieldName)
+	}
+
+	return names
+}
+
+func validateMethods(item *Item) []string {
+	return append(hookMethods(item, "Validate", 0, 1), hookMethods(item, "Validate", 1, 1)...)
+}
+
+// validateHelp returns the constraints of the `validate-help` tag, which
+// describes the Validate hook of the item; they are separated by ';'.
+func validateHelp(item *Item) []string {
+	var constraints []string
+	for _, c := range strings.Split(item.Tag.Get("validate-help"), ";") {
+		if c = strings.TrimSpace(c); len(c) > 0 {
+			constraints = append(constraints, c)
+		}
+	}
+
+	return constraints
+}
+
+func (m *Manager) ReferenceString(format string) (string, error) {
+	entries := m.References()
+
+	switch format {
+	case "md", "markdown":
+		return m.markdownReference(entries), nil
+	case "man", "roff":
+		return m.manReference(entries), nil
+	default:
+		return "", fmt.Errorf("unsupported reference format: '%s'", format)
+	}
+}
+
+func groupReferences(entries []ReferenceEntry) ([]string, map[string][]ReferenceEntry) {
+	var groups []string
+	byGroup := map[string][]ReferenceEntry{}
+	for _, e := range entries {
+		if _, found := byGroup[e.Group]; !found {
+			groups = append(groups, e.Group)
+		}
+		byGroup[e.Group] = append(byGroup[e.Group], e)
+	}
+
+	return groups, byGroup
+}
+
+func (m *Manager) markdownReference(entries []ReferenceEntry) string {
+	escape := func(s string) string {
+		return strings.Replace(s, "|", "\\|", -1)
+	}
+	code := func(s string) string {
+		if len(s) < 1 {
+			return ""
+		}
+		return "`" + escape(s) + "`"
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "# `%s` configuration\n", m.group)
+	fmt.Fprintf(&b, "\nKeys are read from the `%s` section of the config files.\n", m.group)
+
+	groups, byGroup := groupReferences(entries)
+	for _, group := range groups {
+		title := group
+		if len(title) < 1 {
+			title = m.group
+		}
+		fmt.Fprintf(&b, "\n## `%s`\n\n", title)
+		b.WriteString("| Key | Type | Default | Flag | Env | Description | Constraints |\n")
+		b.WriteString("| --- | --- | --- | --- | --- | --- | --- |\n")
+
+		for _, e := range byGroup[group] {
+			fmt.Fprintf(
+				&b,
+				"| %s | %s | %s | %s | %s | %s | %s |\n",
+				code(e.Key),
+				e.Type,
+				code(e.Default),
+				code(e.Flag),
+				code(e.Env),
+				escape(e.Help),
+				escape(strings.Join(e.Constraints, "; ")),
+			)
+		}
+	}
+
+	return b.String()
+}
+
+func (m *Manager) manReference(entries []ReferenceEntry) string {
+	escape := func(s string) string {
+		s = strings.Replace(s, "\\", "\\e", -1)
+		s = strings.Replace(s, "-", "\\-", -1)
+		if strings.HasPrefix(s, ".") || strings.HasPrefix(s, "'") {
+			s = "\\&" + s
+		}
+		return s
+	}
+
+	var b bytes.Buffer
+	b.WriteString(".SH CONFIGURATION\n")
+	fmt.Fprintf(&b, "Keys are read from the \\fB%s\\fR section of the config files.\n", escape(m.group))
+
+	groups, byGroup := groupReferences(entries)
+	for _, group := range groups {
+		title := group
+		if len(title) < 1 {
+			title = m.group
+		}
+		fmt.Fprintf(&b, ".SS %s\n", escape(title))
+
+		for _, e := range byGroup[group] {
+			fmt.Fprintf(&b, ".TP\n.B %s\n", escape(e.Key))
+			if len(e.Help) > 0 {
+				b.WriteString(escape(e.Help) + "\n")
+			}
+
+			b.WriteString(".RS\n.nf\n")
+			fmt.Fprintf(&b, "Type: %s\n", escape(e.Type))
+			if len(e.Default) > 0 {
+				fmt.Fprintf(&b, "Default: %s\n", escape(e.Default))
+			}
+			if len(e.Flag) > 0 {
+				fmt.Fprintf(&b, "Flag: %s\n", escape(e.Flag))
+			}
+			if len(e.Env) > 0 {
+				fmt.Fprintf(&b, "Env: %s\n", escape(e.Env))
+			}
+			for _, c := range e.Constraints {
+				fmt.Fprintf(&b, "Constraint: %s\n", escape(c))
+			}
+			b.WriteString(".fi\n.RE\n")
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,134 @@
+package cvc
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/suite"
+)
+
+type testReferenceLogConfig struct {
+	BaseGroup
+
+	File  string `flag-help:"log output file"`
+	Level string `flag-help:"log level" validate-help:"one of debug, error; case sensitive"`
+}
+
+func (l *testReferenceLogConfig) ParseLevel(i string) (string, error) {
+	return i, nil
+}
+
+func (l *testReferenceLogConfig) ValidateLevel() error {
+	return nil
+}
+
+type testReferenceConfig struct {
+	BaseGroup
+
+	Port int `flag-help:"listen port"`
+	Log  *testReferenceLogConfig
+}
+
+type testReference struct {
+	suite.Suite
+}
+
+func (t *testReference) newManager() *Manager {
+	config := &testReferenceConfig{
+		Port: 80,
+		Log: &testReferenceLogConfig{
+			File:  "naru.log",
+			Level: "debug",
+		},
+	}
+
+	cmd := &cobra.Command{
+		Use:   "naru",
+		Short: "naru",
+	}
+	cmd.SetOutput(ioutil.Discard)
+
+	return NewManager("naru", config, cmd, viper.New())
+}
+
+func (t *testReference) TestReferences() {
+	manager := t.newManager()
+
+	entries := manager.References()
+	t.Equal(3, len(entries))
+
+	t.Equal("port", entries[0].Key)
+	t.Equal("", entries[0].Group)
+	t.Equal("int", entries[0].Type)
+	t.Equal("80", entries[0].Default)
+	t.Equal("--port", entries[0].Flag)
+	t.Equal("NARU_NARU_PORT", entries[0].Env)
+	t.Equal("listen port", entries[0].Help)
+
+	t.Equal("log.file", entries[1].Key)
+	t.Equal("log", entries[1].Group)
+	t.Equal("string", entries[1].Type)
+	t.Equal("naru.log", entries[1].Default)
+	t.Equal("--log-file", entries[1].Flag)
+	t.Equal("NARU_NARU_LOG_FILE", entries[1].Env)
+
+	t.Equal("log.level", entries[2].Key)
+	t.Equal([]string{
+		"parsed by testReferenceLogConfig.ParseLevel",
+		"validated by testReferenceLogConfig.ValidateLevel",
+		"one of debug, error",
+		"case sensitive",
+	}, entries[2].Constraints)
+}
+
+func (t *testReference) TestDefaultAfterMerge() {
+	manager := t.newManager()
+	executeTestManager(t.T(), manager, "--port", "8080")
+
+	_, err := manager.Merge()
+	t.NoError(err)
+
+	entries := manager.References()
+	t.Equal("port", entries[0].Key)
+	t.Equal("80", entries[0].Default)
+}
+
+func (t *testReference) TestMarkdown() {
+	manager := t.newManager()
+
+	s, err := manager.ReferenceString("markdown")
+	t.NoError(err)
+
+	t.Contains(s, "## `naru`\n")
+	t.Contains(s, "## `log`\n")
+	t.Contains(s, "| `log.file` | string | `naru.log` | `--log-file` | `NARU_NARU_LOG_FILE` | log output file |  |\n")
+	t.Contains(s, "; validated by testReferenceLogConfig.ValidateLevel; one of debug, error; case sensitive |\n")
+	t.True(strings.Index(s, "`port`") < strings.Index(s, "`log.file`"))
+}
+
+func (t *testReference) TestMan() {
+	manager := t.newManager()
+
+	s, err := manager.ReferenceString("man")
+	t.NoError(err)
+
+	t.True(strings.HasPrefix(s, ".SH CONFIGURATION\n"))
+	t.Contains(s, ".SS log\n")
+	t.Contains(s, ".TP\n.B log.file\nlog output file\n")
+	t.Contains(s, "Flag: \\-\\-log\\-file\n")
+	t.Contains(s, "Env: NARU_NARU_LOG_FILE\n")
+}
+
+func (t *testReference) TestUnknownFormat() {
+	manager := t.newManager()
+
+	_, err := manager.ReferenceString("html")
+	t.Error(err)
+}
+
+func TestReference(t *testing.T) {
+	suite.Run(t, new(testReference))
+}
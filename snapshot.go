@@ -0,0 +1,132 @@
+package cvc
+
+import (
+	"reflect"
+	"sort"
+)
+
+// Snapshot is the immutable copy of the merged config. The values of Snapshot
+// are shared by the readers, so they must not be modified.
+type Snapshot struct {
+	version    uint64
+	config     interface{}
+	values     map[string]interface{}
+	deprecated []string
+}
+
+func newSnapshot(version uint64, c interface{}, naming NamingStrategy, variants variantTypes) *Snapshot {
+	copied := deepCopy(reflect.ValueOf(c)).Interface()
+
+	_, items := parseConfig(copied, naming, variants)
+
+	values := map[string]interface{}{}
+	for k, item := range items {
+		values[k] = item.Value.Interface()
+	}
+
+	return &Snapshot{
+		version: version,
+		config:  copied,
+		values:  values,
+	}
+}
+
+func (s *Snapshot) Version() uint64 {
+	return s.version
+}
+
+func (s *Snapshot) Config() interface{} {
+	return s.config
+}
+
+func (s *Snapshot) Get(key string) (interface{}, bool) {
+	v, found := s.values[key]
+	return v, found
+}
+
+func (s *Snapshot) Keys() []string {
+	var keys []string
+	for k := range s.values {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+// Deprecated returns the deprecated aliases, which are used by the last Merge,
+// like "'NARU_LOG_FILE' is deprecated; use 'NARU_LOG_PATH'".
+func (s *Snapshot) Deprecated() []string {
+	return s.deprecated
+}
+
+// Snapshot returns the latest snapshot without locking; it is replaced after
+// every successful Merge, SetValue and SetRaw.
+func (m *Manager) Snapshot() *Snapshot {
+	return m.snapshot.Load().(*Snapshot)
+}
+
+func (m *Manager) storeSnapshot() {
+	m.version++
+	s := newSnapshot(m.version, m.c, m.naming, m.variants)
+	if len(m.deprecated) > 0 {
+		s.deprecated = make([]string, len(m.deprecated))
+		copy(s.deprecated, m.deprecated)
+	}
+	m.snapshot.Store(s)
+}
+
+func deepCopy(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return reflect.Zero(v.Type())
+		}
+		n := reflect.New(v.Type().Elem())
+		n.Elem().Set(deepCopy(v.Elem()))
+		return n
+	case reflect.Interface:
+		if v.IsNil() {
+			return reflect.Zero(v.Type())
+		}
+		n := reflect.New(v.Type()).Elem()
+		n.Set(deepCopy(v.Elem()))
+		return n
+	case reflect.Struct:
+		n := reflect.New(v.Type()).Elem()
+		n.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if !n.Field(i).CanSet() {
+				continue
+			}
+			n.Field(i).Set(deepCopy(v.Field(i)))
+		}
+		return n
+	case reflect.Array:
+		n := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			n.Index(i).Set(deepCopy(v.Index(i)))
+		}
+		return n
+	case reflect.Slice:
+		if v.IsNil() {
+			return reflect.Zero(v.Type())
+		}
+		n := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			n.Index(i).Set(deepCopy(v.Index(i)))
+		}
+		return n
+	case reflect.Map:
+		if v.IsNil() {
+			return reflect.Zero(v.Type())
+		}
+		n := reflect.MakeMapWithSize(v.Type(), v.Len())
+		for _, k := range v.MapKeys() {
+			n.SetMapIndex(k, deepCopy(v.MapIndex(k)))
+		}
+		return n
+	default:
+		return v
+	}
+}
@@ -0,0 +1,115 @@
+package cvc
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type testSnapshotLogConfig struct {
+	BaseGroup
+
+	Level string
+}
+
+type testSnapshotConfig struct {
+	BaseGroup
+
+	A     int
+	Names []string
+	Log   *testSnapshotLogConfig
+}
+
+type testSnapshot struct {
+	suite.Suite
+}
+
+func (t *testSnapshot) newManager() (*testSnapshotConfig, *Manager) {
+	config := &testSnapshotConfig{
+		A:     1,
+		Names: []string{"a"},
+		Log:   &testSnapshotLogConfig{Level: "debug"},
+	}
+
+	return config, newTestManager(t.T(), config, nil, nil)
+}
+
+func (t *testSnapshot) TestNew() {
+	config, manager := t.newManager()
+
+	s := manager.Snapshot()
+	t.Equal(uint64(1), s.Version())
+	t.Equal([]string{"a", "log", "log.level", "names"}, s.Keys())
+
+	a, found := s.Get("a")
+	t.True(found)
+	t.Equal(1, a)
+
+	copied := s.Config().(*testSnapshotConfig)
+	t.Equal(config.A, copied.A)
+	t.False(config == copied)
+	t.False(config.Log == copied.Log)
+
+	config.Names[0] = "b"
+	t.Equal("a", copied.Names[0])
+}
+
+func (t *testSnapshot) TestImmutable() {
+	config, manager := t.newManager()
+
+	s := manager.Snapshot()
+
+	t.NoError(manager.SetValue("a", 10))
+	t.NoError(manager.SetRaw("log.level", "error"))
+	t.Equal(10, config.A)
+
+	a, _ := s.Get("a")
+	t.Equal(1, a)
+	level, _ := s.Get("log.level")
+	t.Equal("debug", level)
+
+	latest := manager.Snapshot()
+	t.Equal(uint64(3), latest.Version())
+	a, _ = latest.Get("a")
+	t.Equal(10, a)
+	level, _ = latest.Get("log.level")
+	t.Equal("error", level)
+}
+
+func (t *testSnapshot) TestFailedSetValue() {
+	_, manager := t.newManager()
+
+	t.Error(manager.SetValue("b", 10))
+	t.Equal(uint64(1), manager.Snapshot().Version())
+}
+
+func (t *testSnapshot) TestMerge() {
+	_, manager := t.newManager()
+
+	executeTestManager(t.T(), manager, "--a", "20")
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		for i := 0; i < 100; i++ {
+			s := manager.Snapshot()
+			a, _ := s.Get("a")
+			t.Contains([]interface{}{1, 20}, a)
+		}
+	}()
+
+	_, err := manager.Merge()
+	t.NoError(err)
+	wg.Wait()
+
+	s := manager.Snapshot()
+	t.Equal(uint64(2), s.Version())
+	t.Equal(20, s.Config().(*testSnapshotConfig).A)
+}
+
+func TestSnapshot(t *testing.T) {
+	suite.Run(t, new(testSnapshot))
+}
@@ -0,0 +1,239 @@
+package cvc
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// variantTypes is the names of the variants of the unions by the key of union
+// and the type of variant.
+type variantTypes map[string]map[reflect.Type]string
+
+// RegisterVariant registers the variant of the union, which is the field of
+// the interface type with the `union:"true"` tag, like `storage`. The variant is selected by the
+// discriminator, `storage.type` by default or the `discriminator` tag, and
+// the fields of the variant are the keys under the name, like
+// `storage.s3.bucket`. v is the template of the variant and it must be the
+// pointer of struct, which implements the interface. RegisterVariant must be
+// called before the flags are parsed.
+func (m *Manager) RegisterVariant(key, name string, v interface{}) error {
+	m.Lock()
+
+	union, found := m.m[key]
+	if !found {
+		m.Unlock()
+		return ErrorKeyNotFound.Clone().Set("key", key)
+	}
+	if !union.IsUnion {
+		m.Unlock()
+		return fmt.Errorf("'%s' is not union", key)
+	}
+	if variant := union.variant(name); variant != nil || name == union.discriminator.FieldName {
+		m.Unlock()
+		return fmt.Errorf("variant already registered: '%s'", name)
+	}
+
+	vv := reflect.ValueOf(v)
+	if !vv.IsValid() || vv.Kind() != reflect.Ptr || vv.IsNil() || !isStructType(vv.Type()) ||
+		!vv.Type().Implements(union.Value.Type()) {
+		m.Unlock()
+		return ErrorNotAssignable.Clone().Set("key", key).Set("value", fmt.Sprintf("%T", v))
+	}
+
+	if m.variants == nil {
+		m.variants = variantTypes{}
+	}
+	if m.variants[key] == nil {
+		m.variants[key] = map[reflect.Type]string{}
+	}
+	m.variants[key][vv.Type()] = name
+
+	// the current value of union is used for the variant of the same type
+	if !union.Value.IsNil() && union.Value.Elem().Type() == vv.Type() {
+		vv = union.Value.Elem()
+		if len(union.discriminator.Value.String()) < 1 {
+			union.discriminator.Value.SetString(name)
+		}
+	} else {
+		vv = deepCopy(vv)
+	}
+
+	variant, items := parseVariant(m.c, union, name, vv)
+	union.Children = append(union.Children, variant)
+
+	for k, item := range items {
+		m.m[k] = item
+		m.keys[strings.ToLower(k)] = item
+		for _, a := range item.Aliases() {
+			m.aliases[strings.ToLower(a)] = item
+		}
+		if !item.inCollection() {
+			m.fs[item.FlagName()] = item
+		}
+	}
+	m.cacheEnvNames(items)
+
+	union.selectVariant()
+
+	m.Unlock()
+
+	for _, item := range items {
+		if err := m.setDefault(item); err != nil {
+			m.Logger().Error("failed to set default", "item", item.FullName(), "error", err)
+		}
+	}
+
+	for _, item := range items {
+		if err := m.setFlag(item); err != nil {
+			return err
+		}
+	}
+
+	m.Lock()
+	m.storeSnapshot()
+	m.Unlock()
+
+	return nil
+}
+
+// parseUnion parses the union; the discriminator is the child of union, and
+// the current value is parsed as the variant if the type of it is registered.
+func parseUnion(c interface{}, union *Item, tag reflect.StructTag) map[string]*Item {
+	name := tag.Get("discriminator")
+	if len(name) < 1 {
+		name = "type"
+	}
+
+	// the default of union selects the variant only when the union is empty
+	var dtag reflect.StructTag
+	if d, found := tag.Lookup("default"); found && union.Value.IsNil() {
+		dtag = reflect.StructTag(fmt.Sprintf("default:%q", d))
+	}
+
+	d := &Item{
+		FieldName: name,
+		Value:     reflect.New(reflect.TypeOf("")).Elem(),
+		Group:     union,
+		Tag:       dtag,
+	}
+	d.cachedPrefixes = d.prefixes()
+	d.fullName = d.FullName()
+	d.flagType = d.FlagType()
+
+	union.discriminator = d
+	union.Children = []*Item{d}
+
+	m := map[string]*Item{d.fullName: d}
+	if union.Value.IsNil() {
+		return m
+	}
+
+	root := union
+	for root.Group != nil {
+		root = root.Group
+	}
+
+	vname, found := root.variants[union.fullName][union.Value.Elem().Type()]
+	if !found {
+		return m
+	}
+
+	d.Value.SetString(vname)
+
+	variant, items := parseVariant(c, union, vname, union.Value.Elem())
+	union.Children = append(union.Children, variant)
+	for k, v := range items {
+		m[k] = v
+	}
+
+	return m
+}
+
+func parseVariant(c interface{}, union *Item, name string, v reflect.Value) (*Item, map[string]*Item) {
+	item := &Item{
+		FieldName: name,
+		Value:     v,
+		Group:     union,
+		IsGroup:   true,
+	}
+	item.cachedPrefixes = item.prefixes()
+	item.fullName = item.FullName()
+
+	m := parseConfigField(c, v.Type(), v, []*Item{item})
+	m[item.fullName] = item
+
+	return item, m
+}
+
+// variant returns the variant of the name.
+func (c *Item) variant(name string) *Item {
+	for _, i := range c.Children {
+		if i.IsGroup && i.FieldName == name {
+			return i
+		}
+	}
+
+	return nil
+}
+
+// selectVariant sets the variant, which is selected by the discriminator, to
+// the union.
+func (c *Item) selectVariant() {
+	if v := c.variant(c.discriminator.Value.String()); v != nil {
+		c.Value.Set(v.Value)
+	}
+}
+
+// unselected checks whether the item is the variant, which is not selected,
+// or in it.
+func (c *Item) unselected() bool {
+	for i := c; i.Group != nil; i = i.Group {
+		if i.IsGroup && i.Group.IsUnion && i != i.Group.variant(i.Group.discriminator.Value.String()) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// validateUnion checks that the discriminator selects the registered variant
+// and only the fields of the selected variant are set.
+func (c *Item) validateUnion() error {
+	selected := c.discriminator.Value.String()
+	if len(selected) > 0 && c.variant(selected) == nil {
+		return fmt.Errorf("unknown %s: '%s'", c.discriminator.FullName(), selected)
+	}
+
+	for _, v := range c.Children {
+		if !v.IsGroup || v.FieldName == selected {
+			continue
+		}
+
+		if i := changedItem(v); i != nil {
+			if len(selected) < 1 {
+				return fmt.Errorf("'%s' is set, but %s is not set", i.FullName(), c.discriminator.FullName())
+			}
+			return fmt.Errorf("'%s' is set, but %s is '%s'", i.FullName(), c.discriminator.FullName(), selected)
+		}
+	}
+
+	return nil
+}
+
+func changedItem(group *Item) *Item {
+	for _, i := range group.Children {
+		if i.IsGroup {
+			if c := changedItem(i); c != nil {
+				return c
+			}
+			continue
+		}
+
+		if i.Changed() {
+			return i
+		}
+	}
+
+	return nil
+}
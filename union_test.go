@@ -0,0 +1,167 @@
+package cvc
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type testUnionStorage interface {
+	Validate() error
+}
+
+type testUnionS3 struct {
+	BaseGroup
+	Bucket string
+	Region string `default:"us-east-1"`
+}
+
+func (s *testUnionS3) Validate() error {
+	if len(s.Bucket) < 1 {
+		return errors.New("empty bucket")
+	}
+
+	return nil
+}
+
+type testUnionFS struct {
+	BaseGroup
+	Path string `default:"/tmp"`
+}
+
+type testUnionConfig struct {
+	BaseGroup
+	Storage testUnionStorage `union:"true" default:"fs"`
+}
+
+type testUnion struct {
+	suite.Suite
+}
+
+func (t *testUnion) newManager(config *testUnionConfig, envs map[string]string, args ...string) *Manager {
+	manager := newTestManager(t.T(), config, envs, nil)
+
+	t.NoError(manager.RegisterVariant("storage", "s3", &testUnionS3{}))
+	t.NoError(manager.RegisterVariant("storage", "fs", &testUnionFS{}))
+
+	executeTestManager(t.T(), manager, args...)
+
+	return manager
+}
+
+func (t *testUnion) TestKeys() {
+	config := &testUnionConfig{}
+	manager := t.newManager(config, nil)
+
+	for _, k := range []string{"storage.type", "storage.s3.bucket", "storage.s3.region", "storage.fs.path"} {
+		t.Contains(manager.Map(), k)
+	}
+	t.NotNil(manager.FlagSet().Lookup("storage-s3-bucket"))
+	t.Contains(manager.Envs(), "NARU_STORAGE_FS_PATH")
+
+	// selected by the default of discriminator
+	t.Equal(&testUnionFS{Path: "/tmp"}, config.Storage)
+}
+
+func (t *testUnion) TestSelect() {
+	config := &testUnionConfig{}
+	manager := t.newManager(config, map[string]string{
+		"NARU_STORAGE_TYPE":      "s3",
+		"NARU_STORAGE_S3_BUCKET": "env",
+	}, "--storage-s3-bucket", "naru")
+	manager.SetViperConfig("yml", []byte(`
+naru:
+  storage:
+    s3:
+      region: ap-northeast-2
+`))
+
+	_, err := manager.Merge()
+	t.NoError(err)
+
+	t.Equal(&testUnionS3{Bucket: "naru", Region: "ap-northeast-2"}, config.Storage)
+
+	v, found := manager.Snapshot().Get("storage.s3.bucket")
+	t.True(found)
+	t.Equal("naru", v)
+	_, found = manager.Snapshot().Get("storage.fs.path")
+	t.False(found)
+}
+
+func (t *testUnion) TestValidateVariant() {
+	manager := t.newManager(&testUnionConfig{}, nil, "--storage-type", "s3")
+
+	key, err := manager.Merge()
+	t.Equal("storage.s3", key)
+	t.True(errors.Is(err, ErrorValidation))
+}
+
+func (t *testUnion) TestOtherVariantSet() {
+	manager := t.newManager(&testUnionConfig{}, nil, "--storage-s3-bucket", "naru")
+
+	key, err := manager.Merge()
+	t.Equal("storage", key)
+	t.True(errors.Is(err, ErrorValidation))
+	t.Contains(err.Error(), "'storage.s3.bucket' is set, but storage.type is 'fs'")
+}
+
+func (t *testUnion) TestUnknownVariant() {
+	manager := t.newManager(&testUnionConfig{}, nil, "--storage-type", "memory")
+
+	key, err := manager.Merge()
+	t.Equal("storage", key)
+	t.Contains(err.Error(), "unknown storage.type: 'memory'")
+}
+
+func (t *testUnion) TestCurrentValue() {
+	config := &testUnionConfig{Storage: &testUnionS3{Bucket: "a"}}
+	manager := t.newManager(config, nil)
+
+	_, err := manager.Merge()
+	t.NoError(err)
+
+	t.Equal(&testUnionS3{Bucket: "a", Region: "us-east-1"}, config.Storage)
+}
+
+func (t *testUnion) TestRegister() {
+	manager := newTestManager(t.T(), &testUnionConfig{}, nil, nil)
+
+	t.NoError(manager.RegisterVariant("storage", "s3", &testUnionS3{}))
+	t.Error(manager.RegisterVariant("storage", "s3", &testUnionS3{}))
+	t.True(errors.Is(manager.RegisterVariant("storage", "memory", testUnionS3{}), ErrorNotAssignable))
+	t.True(errors.Is(manager.RegisterVariant("unknown", "s3", &testUnionS3{}), ErrorKeyNotFound))
+}
+
+type testUnionName string
+
+func (n testUnionName) String() string {
+	return string(n)
+}
+
+type testUnionPlainConfig struct {
+	BaseGroup
+	Name fmt.Stringer
+}
+
+func (t *testUnion) TestPlainInterface() {
+	config := &testUnionPlainConfig{Name: testUnionName("naru")}
+
+	manager := newTestManager(t.T(), config, nil, nil)
+
+	item, found := manager.Get("name")
+	t.True(found)
+	t.False(item.IsUnion)
+	t.NotContains(manager.Map(), "name.type")
+	t.Error(manager.RegisterVariant("name", "a", &testUnionFS{}))
+
+	_, err := manager.Merge()
+	t.NoError(err)
+	t.Equal(testUnionName("naru"), config.Name)
+	t.Equal(testUnionName("naru"), MustGet[fmt.Stringer](manager, "name"))
+}
+
+func TestUnion(t *testing.T) {
+	suite.Run(t, new(testUnion))
+}
@@ -1,11 +1,14 @@
 package cvc
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"reflect"
-	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 	"unicode"
 
 	"github.com/spf13/cast"
@@ -13,19 +16,26 @@ import (
 )
 
 var (
-	groupType reflect.Type
+	groupType    reflect.Type
+	durationType reflect.Type = reflect.TypeOf(time.Duration(0))
+	contextType  reflect.Type = reflect.TypeOf((*context.Context)(nil)).Elem()
 )
 
 func init() {
-	groupType = reflect.TypeOf((*Group)(nil)).Elem()
+	groupType = reflect.TypeOf((*interface{ ThisIsGroup() })(nil)).Elem()
 }
 
 type StructMethod struct {
 	Func reflect.Value
 	Body reflect.Value
+	call func(reflect.Value, []reflect.Value) []reflect.Value
 }
 
 func (m StructMethod) Call(args ...reflect.Value) []reflect.Value {
+	if m.call != nil {
+		return m.call(m.Body, args)
+	}
+
 	args = append(args[:0], append([]reflect.Value{m.Body}, args[0:]...)...)
 
 	return m.Func.Call(args)
@@ -52,16 +62,13 @@ func NormalizeVar(s string, sep string) string {
 		return s
 	}
 
-	if len(sep) > 0 {
-		re := regexp.MustCompile(regexp.QuoteMeta(sep))
-		if re.FindIndex([]byte(s)) != nil {
-			var r []string
-			for _, d := range re.Split(s, -1) {
-				r = append(r, NormalizeVar(d, ""))
-			}
-
-			return strings.Join(r, sep)
+	if len(sep) > 0 && strings.Contains(s, sep) {
+		var r []string
+		for _, d := range strings.Split(s, sep) {
+			r = append(r, NormalizeVar(d, ""))
 		}
+
+		return strings.Join(r, sep)
 	}
 
 	rn := []rune(s)
@@ -169,9 +176,61 @@ func getConfigTypeByValue(v reflect.Value) string {
 	}
 }
 
+func newFlagInput(t string) (interface{}, error) {
+	switch t {
+	case "BoolVar":
+		return new(bool), nil
+	case "IntVar":
+		return new(int), nil
+	case "Int8Var":
+		return new(int8), nil
+	case "Int16Var":
+		return new(int16), nil
+	case "Int32Var":
+		return new(int32), nil
+	case "Int64Var":
+		return new(int64), nil
+	case "UintVar":
+		return new(uint), nil
+	case "Uint8Var":
+		return new(uint8), nil
+	case "Uint16Var":
+		return new(uint16), nil
+	case "Uint32Var":
+		return new(uint32), nil
+	case "Uint64Var":
+		return new(uint64), nil
+	case "Float32Var":
+		return new(float32), nil
+	case "Float64Var":
+		return new(float64), nil
+	case "StringVar":
+		return new(string), nil
+	case "DurationVar":
+		return new(time.Duration), nil
+	default:
+		return nil, fmt.Errorf("value type, '%s' is not supported by flag", t)
+	}
+}
+
 func GetMethodByName(i interface{}, name string, numIn, numOut int) (m StructMethod, found bool) {
+	// the method, which is added after the binding was generated, is found by
+	// reflection
+	if methods, registered := lookupBinding(reflect.TypeOf(i)); registered {
+		if b, bound := methods[name]; bound {
+			m = StructMethod{Func: reflect.ValueOf(b.fn), Body: reflect.ValueOf(i), call: b.call}
+			found = matchArity(m.Func.Type(), numIn, numOut)
+
+			return
+		}
+	}
+
+	if i == nil {
+		return
+	}
+
 	var method reflect.Method
-	method, found = reflect.TypeOf(i).MethodByName(name)
+	method, found = metaOf(reflect.TypeOf(i)).methods[name]
 	if !found {
 		return
 	}
@@ -183,39 +242,48 @@ func GetMethodByName(i interface{}, name string, numIn, numOut int) (m StructMet
 	}
 
 	m = StructMethod{Func: method.Func, Body: reflect.ValueOf(i)}
-	if m.NumIn() != numIn || m.NumOut() != numOut {
-		return
-	}
+	found = matchArity(m.Func.Type(), numIn, numOut)
 
-	found = true
 	return
 }
 
+// matchArity checks the numbers of the arguments and the returns of the
+// method expression; the receiver is not counted.
+func matchArity(t reflect.Type, numIn, numOut int) bool {
+	return t.NumIn()-1 == numIn && t.NumOut() == numOut
+}
+
 func GetFuncFromItem(item *Item, name string, numIn, numOut int) []StructMethod {
 	var fns []StructMethod
 
 	var parseFunc StructMethod
 	var found bool
-	parseFunc, found = GetMethodByName(
-		item.Value.Interface(),
-		name,
-		numIn,
-		numOut,
-	)
-	if found && !parseFunc.Empty() {
-		parseFunc.Body = item.Value
-		fns = append(fns, parseFunc)
+
+	// the methods of the value of union are called by the variant
+	body := methodBody(item)
+	if !item.IsUnion {
+		parseFunc, found = GetMethodByName(
+			body.Interface(),
+			name,
+			numIn,
+			numOut,
+		)
+		if found && !parseFunc.Empty() {
+			parseFunc.Body = body
+			fns = append(fns, parseFunc)
+		}
 	}
 
 	if item.Group != nil {
+		body = methodBody(item.Group)
 		parseFunc, found = GetMethodByName(
-			item.Group.Value.Interface(),
-			fmt.Sprintf("%s%s", name, item.FieldName),
+			body.Interface(),
+			name+item.FieldName,
 			numIn,
 			numOut,
 		)
 		if found && !parseFunc.Empty() {
-			parseFunc.Body = item.Group.Value
+			parseFunc.Body = body
 			fns = append(fns, parseFunc)
 		}
 	}
@@ -223,6 +291,16 @@ func GetFuncFromItem(item *Item, name string, numIn, numOut int) []StructMethod
 	return fns
 }
 
+// methodBody returns the pointer of the struct group, which is not pointer,
+// to find the methods of the pointer receiver.
+func methodBody(item *Item) reflect.Value {
+	if item.IsGroup && item.Value.Kind() == reflect.Struct && item.Value.CanAddr() {
+		return item.Value.Addr()
+	}
+
+	return item.Value
+}
+
 func GetFuncFromItemStruct(item *Item, name string, numIn, numOut int) []StructMethod {
 	var fns []StructMethod
 
@@ -241,7 +319,7 @@ func GetFuncFromItemStruct(item *Item, name string, numIn, numOut int) []StructM
 	if item.Group != nil {
 		parseFunc, found = GetMethodByName(
 			item.Group.Value.Interface(),
-			fmt.Sprintf("%s%s", name, item.FieldName),
+			name+item.FieldName,
 			numIn,
 			numOut,
 		)
@@ -254,7 +332,7 @@ func GetFuncFromItemStruct(item *Item, name string, numIn, numOut int) []StructM
 }
 
 func GetFlagValue(item *Item) (reflect.Value, error) {
-	fns := GetFuncFromItem(item, "FlagValue", 1, 2)
+	fns := GetFuncFromItem(item, "FlagValue", 0, 1)
 	for _, fn := range fns {
 		vs := fn.Call()
 		return vs[0], nil
@@ -328,13 +406,15 @@ func getKeyFromConfig(prefix string, m map[string]interface{}) []string {
 	return filtered
 }
 
-func parseConfig(c interface{}) (*Item, map[string]*Item) {
+func parseConfig(c interface{}, naming NamingStrategy, variants variantTypes) (*Item, map[string]*Item) {
 	root := &Item{
 		FieldName: "",
 		Value:     reflect.ValueOf(c),
 		Group:     nil,
 		Tag:       "",
 		IsGroup:   true,
+		naming:    naming,
+		variants:  variants,
 	}
 
 	return root, parseConfigField(c, reflect.TypeOf(c), root.Value, []*Item{root})
@@ -347,25 +427,34 @@ func parseConfigField(c interface{}, t reflect.Type, v reflect.Value, parents []
 		t = t.Elem()
 	}
 
+	sv := v
+	if sv.Kind() == reflect.Ptr {
+		sv = sv.Elem()
+	}
+
 	m := map[string]*Item{}
-	for i := 0; i < t.NumField(); i++ {
-		ft := t.Field(i)
-
-		var fv reflect.Value
-		if v.Kind() == reflect.Ptr {
-			fv = v.Elem().Field(i)
-		} else {
-			fv = v.Field(i)
-		}
+	for _, f := range metaOf(t).fields {
+		ft := f.field
 
-		if fv.Kind() == reflect.Ptr && fv.IsNil() {
+		fv := sv.Field(f.index)
+		// the pointer of scalar value stays nil until it is set
+		if fv.Kind() == reflect.Ptr && fv.IsNil() && fv.CanSet() && isStructType(ft.Type) {
 			fv.Set(reflect.New(ft.Type.Elem()))
-			fv = v.Elem().Field(i)
 		}
 
-		if ft.Type == reflect.TypeOf(BaseGroup{}) {
+		// the fields of the embedded struct are flattened into the group
+		if f.isEmbedded {
+			if fv.Kind() == reflect.Ptr && fv.IsNil() {
+				continue
+			}
+
+			for k, v := range parseConfigField(c, ft.Type, fv, parents) {
+				m[k] = v
+			}
 			continue
-		} else if !fv.CanSet() {
+		}
+
+		if !fv.CanSet() {
 			continue
 		}
 
@@ -375,80 +464,197 @@ func parseConfigField(c interface{}, t reflect.Type, v reflect.Value, parents []
 			Group:     group,
 			Tag:       ft.Tag,
 		}
+		item.cachedPrefixes = item.prefixes()
+		item.fullName = item.FullName()
 		group.Children = append(group.Children, item)
 
-		m[item.FullName()] = item
-		if ft.Type.Implements(groupType) {
+		m[item.fullName] = item
+		switch {
+		case f.isList:
+			item.IsGroup = true
+			item.IsList = true
+			for k, v := range parseListElements(c, item) {
+				m[k] = v
+			}
+		case f.isMap:
+			item.IsGroup = true
+			item.IsMap = true
+			for k, v := range parseMapElements(c, item) {
+				m[k] = v
+			}
+		case f.isUnion:
+			item.IsGroup = true
+			item.IsUnion = true
+			for k, v := range parseUnion(c, item, ft.Tag) {
+				m[k] = v
+			}
+		case f.isGroup:
 			item.IsGroup = true
 			n := parseConfigField(c, ft.Type, fv, append(parents, item))
 			for k, v := range n {
 				m[k] = v
 			}
+		default:
+			item.flagType = item.FlagType()
 		}
 	}
 
 	return m
 }
 
-func CallParseFunc(f StructMethod, i interface{}) (interface{}, error) {
-	rs := f.Call(reflect.ValueOf(i))
-	v := rs[0].Interface()
-	err := rs[1].Interface()
-	if err == nil {
-		return v, nil
+// parseListElements parses the elements of the list of groups; the element is
+// the group named by its index, like `upstreams.0.host`.
+func parseListElements(c interface{}, list *Item) map[string]*Item {
+	list.Children = nil
+
+	m := map[string]*Item{}
+	for i := 0; i < list.Value.Len(); i++ {
+		item, n := parseListElement(c, list, i, list.Value.Index(i))
+		list.Children = append(list.Children, item)
+
+		for k, v := range n {
+			m[k] = v
+		}
 	}
 
-	e, found := err.(error)
-	if found {
-		return nil, e
+	return m
+}
+
+func parseListElement(c interface{}, list *Item, i int, ev reflect.Value) (*Item, map[string]*Item) {
+	if ev.Kind() == reflect.Ptr && ev.IsNil() {
+		ev.Set(reflect.New(ev.Type().Elem()))
 	}
 
-	log.Error(
-		"ParseXX() return is not error type",
-		"return", err,
-		"kind", rs[1].Type().Kind(),
-	)
+	item := &Item{
+		FieldName: strconv.Itoa(i),
+		Value:     ev,
+		Group:     list,
+		IsGroup:   true,
+	}
+	item.cachedPrefixes = item.prefixes()
+	item.fullName = item.FullName()
 
-	return nil, ErrorMethodNotFound
+	m := parseConfigField(c, ev.Type(), ev, []*Item{item})
+	m[item.fullName] = item
+
+	return item, m
 }
 
-func CallValidateFunc(f StructMethod) error {
-	rs := f.Call()
-	err := rs[0].Interface()
-	if err == nil {
-		return nil
+// parseMapElements parses the entries of the map of groups in the order of
+// the keys; the entry is the group named by its key, like
+// `databases.primary.dsn`.
+func parseMapElements(c interface{}, group *Item) map[string]*Item {
+	group.Children = nil
+
+	var keys []string
+	for _, k := range group.Value.MapKeys() {
+		keys = append(keys, k.String())
 	}
+	sort.Strings(keys)
 
-	e, found := err.(error)
-	if found {
-		return e
+	m := map[string]*Item{}
+	for _, k := range keys {
+		key := reflect.ValueOf(k).Convert(group.Value.Type().Key())
+
+		ev := group.Value.MapIndex(key)
+		if ev.IsNil() {
+			ev = reflect.New(ev.Type().Elem())
+			group.Value.SetMapIndex(key, ev)
+		}
+
+		item, n := parseMapElement(c, group, k, ev)
+		group.Children = append(group.Children, item)
+
+		for k, v := range n {
+			m[k] = v
+		}
 	}
 
-	log.Error(
-		"ValidateXXX() return is not error type",
-		"return", err,
-		"kind", rs[0].Type().Kind(),
-	)
+	return m
+}
 
-	return ErrorMethodNotFound
+func parseMapElement(c interface{}, group *Item, key string, ev reflect.Value) (*Item, map[string]*Item) {
+	item := &Item{
+		FieldName: key,
+		Value:     ev,
+		Group:     group,
+		IsGroup:   true,
+	}
+	item.cachedPrefixes = item.prefixes()
+	item.fullName = item.FullName()
+
+	m := parseConfigField(c, ev.Type(), ev, []*Item{item})
+	m[item.fullName] = item
+
+	return item, m
+}
+
+func CallParseFunc(f StructMethod, i interface{}) (interface{}, error) {
+	return callParseFunc(log, f, i)
+}
+
+func callParseFunc(logger Logger, f StructMethod, i interface{}) (interface{}, error) {
+	if i != nil && !reflect.TypeOf(i).AssignableTo(f.In(0)) {
+		c, err := convertValue(f.In(0), i)
+		if err != nil {
+			return nil, err
+		}
+		i = c
+	}
+
+	rs := f.Call(reflect.ValueOf(i))
+	if err := returnedError(logger, "ParseXX()", rs[1]); err != nil {
+		return nil, err
+	}
+
+	return rs[0].Interface(), nil
+}
+
+func CallValidateFunc(f StructMethod) error {
+	return CallValidateFuncContext(context.Background(), f)
+}
+
+// CallValidateFuncContext calls `Validate() error` or
+// `Validate(context.Context) error`.
+func CallValidateFuncContext(ctx context.Context, f StructMethod) error {
+	return callValidateFunc(ctx, log, f)
+}
+
+func callValidateFunc(ctx context.Context, logger Logger, f StructMethod) error {
+	var rs []reflect.Value
+	if f.NumIn() == 2 && f.In(0) == contextType {
+		rs = f.Call(reflect.ValueOf(&ctx).Elem())
+	} else {
+		rs = f.Call()
+	}
+
+	return returnedError(logger, "ValidateXXX()", rs[0])
 }
 
 func CallMergeFunc(f StructMethod) error {
-	rs := f.Call()
-	err := rs[0].Interface()
+	return returnedError(log, "MergeXXX()", f.Call()[0])
+}
+
+func CallHookFunc(f StructMethod) error {
+	return returnedError(log, "hook", f.Call()[0])
+}
+
+// returnedError returns the error, which is returned by the method; the
+// return, which is not error, is logged.
+func returnedError(logger Logger, name string, r reflect.Value) error {
+	err := r.Interface()
 	if err == nil {
 		return nil
 	}
 
-	e, found := err.(error)
-	if found {
+	if e, found := err.(error); found {
 		return e
 	}
 
-	log.Error(
-		"MergeXXX() return is not error type",
+	logger.Error(
+		name+" return is not error type",
 		"return", err,
-		"kind", rs[0].Type().Kind(),
+		"kind", r.Type().Kind(),
 	)
 
 	return ErrorMethodNotFound
@@ -0,0 +1,73 @@
+package cvc
+
+import (
+	"context"
+	"errors"
+)
+
+// AddValidator adds the validator of the whole merged config. The validators
+// are called in order after the groups are validated and merged, with the
+// snapshot of the merged config, which is not stored yet. To report the
+// invalid key, the validator returns *ValidationError.
+func (m *Manager) AddValidator(fn func(*Snapshot) error) {
+	m.Lock()
+	defer m.Unlock()
+
+	m.validators = append(m.validators, fn)
+}
+
+func (m *Manager) runValidators() (string, error) {
+	m.RLock()
+	validators := m.validators
+	m.RUnlock()
+
+	if len(validators) < 1 {
+		return "", nil
+	}
+
+	m.RLock()
+	s := newSnapshot(m.version, m.c, m.naming, m.variants)
+	m.RUnlock()
+
+	for _, fn := range validators {
+		if err := fn(s); err != nil {
+			e, ok := m.validationError("", SourceValidator, err).(*ValidationError)
+			if !ok {
+				return "", err
+			}
+			if len(e.Source) < 1 {
+				e.Source = SourceValidator
+			}
+			return e.Key, e
+		}
+	}
+
+	return "", nil
+}
+
+// validationError wraps err by *ValidationError; the Input is the value of
+// item of the key, and the empty key is for the root.
+func (m *Manager) validationError(key, source string, err error) error {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+
+	e, ok := err.(*ValidationError)
+	if !ok {
+		e = &ValidationError{Key: key, Source: source, Err: err}
+	}
+
+	if e.Input == nil {
+		m.RLock()
+		item, found := m.get(e.Key)
+		if !found && e.Key == m.root.FullName() {
+			item, found = m.root, true
+		}
+		if found {
+			e.Input = item.Value.Interface()
+		}
+		m.RUnlock()
+	}
+
+	return e
+}
@@ -0,0 +1,222 @@
+package cvc
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type testValidateGroupConfig struct {
+	BaseGroup
+
+	Port int
+
+	validate func(context.Context) error
+}
+
+func (g *testValidateGroupConfig) Validate(ctx context.Context) error {
+	if g.validate == nil {
+		return nil
+	}
+
+	return g.validate(ctx)
+}
+
+type testValidateConfig struct {
+	BaseGroup
+
+	Home    string
+	Network *testValidateGroupConfig
+	Storage *testValidateGroupConfig
+}
+
+func (c *testValidateConfig) ValidateHome(ctx context.Context) error {
+	if c.Home == "/" {
+		return errors.New("root is not allowed")
+	}
+
+	return ctx.Err()
+}
+
+type testValidate struct {
+	suite.Suite
+}
+
+func (t *testValidate) newManager(args ...string) (*testValidateConfig, *Manager) {
+	config := &testValidateConfig{
+		Home:    "/home/naru",
+		Network: &testValidateGroupConfig{},
+		Storage: &testValidateGroupConfig{},
+	}
+
+	manager := newTestManager(t.T(), config, nil, args)
+
+	return config, manager
+}
+
+func (t *testValidate) TestGroupWithContext() {
+	config, manager := t.newManager("--network-port", "80", "--home", "/")
+
+	_, err := manager.Merge()
+	t.EqualError(err, "invalid 'home': root is not allowed")
+	t.Equal(80, config.Network.Port)
+}
+
+func (t *testValidate) TestTimeout() {
+	config, manager := t.newManager()
+	config.Storage.validate = func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+
+	_, err := manager.MergeContext(ctx)
+	t.Equal(context.DeadlineExceeded, err)
+}
+
+func (t *testValidate) TestTimeoutWaitChildren() {
+	config, manager := t.newManager()
+
+	var finished int32
+	config.Storage.validate = func(context.Context) error {
+		time.Sleep(time.Millisecond * 100)
+		atomic.StoreInt32(&finished, 1)
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+
+	_, err := manager.MergeContext(ctx)
+	t.Equal(context.DeadlineExceeded, err)
+	t.Equal(int32(1), atomic.LoadInt32(&finished))
+}
+
+func (t *testValidate) TestConcurrent() {
+	config, manager := t.newManager()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	wait := func(ctx context.Context) error {
+		wg.Done()
+
+		done := make(chan struct{})
+		go func() {
+			wg.Wait()
+			close(done)
+		}()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-done:
+			return nil
+		}
+	}
+
+	// the both groups must be validated at the same time in the first
+	// validation.
+	var networkOnce, storageOnce sync.Once
+	config.Network.validate = func(ctx context.Context) (err error) {
+		networkOnce.Do(func() { err = wait(ctx) })
+		return
+	}
+	config.Storage.validate = func(ctx context.Context) (err error) {
+		storageOnce.Do(func() { err = wait(ctx) })
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	_, err := manager.MergeContext(ctx)
+	t.NoError(err)
+}
+
+func (t *testValidate) TestFirstError() {
+	config, manager := t.newManager()
+	config.Network.validate = func(context.Context) error {
+		time.Sleep(time.Millisecond * 20)
+		return errors.New("network")
+	}
+	config.Storage.validate = func(context.Context) error {
+		return errors.New("storage")
+	}
+
+	for i := 0; i < 3; i++ {
+		n, err := manager.Merge()
+		t.EqualError(err, "invalid 'network': network")
+		t.Equal("network", n)
+	}
+}
+
+func (t *testValidate) TestValidator() {
+	config, manager := t.newManager("--network-port", "80")
+
+	var called []string
+	manager.AddValidator(func(s *Snapshot) error {
+		called = append(called, "first")
+
+		home, found := s.Get("home")
+		t.True(found)
+		t.Equal("/home/naru", home)
+
+		return nil
+	})
+	manager.AddValidator(func(s *Snapshot) error {
+		called = append(called, "port")
+
+		port, _ := s.Get("network.port")
+		if port.(int) == 80 {
+			return &ValidationError{Key: "network.port", Err: errors.New("80 is not allowed")}
+		}
+
+		return nil
+	})
+
+	n, err := manager.Merge()
+	t.Equal("network.port", n)
+	t.EqualError(err, "invalid 'network.port': 80 is not allowed")
+	t.Equal([]string{"first", "port"}, called)
+
+	// the failed merge does not replace the snapshot
+	port, _ := manager.Snapshot().Get("network.port")
+	t.Equal(0, port)
+	t.Equal(80, config.Network.Port)
+}
+
+func (t *testValidate) TestValidatorAfterValidate() {
+	_, manager := t.newManager("--home", "/")
+
+	var called bool
+	manager.AddValidator(func(s *Snapshot) error {
+		called = true
+		return nil
+	})
+
+	_, err := manager.Merge()
+	t.EqualError(err, "invalid 'home': root is not allowed")
+	t.False(called)
+}
+
+func (t *testValidate) TestValidatorCanceled() {
+	_, manager := t.newManager()
+
+	manager.AddValidator(func(*Snapshot) error {
+		return context.Canceled
+	})
+
+	_, err := manager.Merge()
+	t.True(errors.Is(err, context.Canceled))
+}
+
+func TestValidate(t *testing.T) {
+	suite.Run(t, new(testValidate))
+}
@@ -0,0 +1,97 @@
+package cvc
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Get returns the value of the item by key as T. The pointer and non-pointer
+// form of group fields can be used both; for example `Get[LogConfig]` and
+// `Get[*LogConfig]` for `Log *LogConfig`.
+func Get[T any](m *Manager, key string) (T, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	var t T
+	item, found := m.get(key)
+	if !found {
+		return t, ErrorKeyNotFound.Clone().Set("key", key)
+	}
+
+	v, err := assignableValue(key, item.Value, reflect.TypeOf(&t).Elem())
+	if err != nil {
+		return t, err
+	}
+
+	// the unset interface field
+	i := v.Interface()
+	if i == nil {
+		return t, nil
+	}
+
+	r, ok := i.(T)
+	if !ok {
+		return t, ErrorNotAssignable.Clone().
+			Set("key", key).
+			Set("from", fmt.Sprintf("%T", i)).
+			Set("to", reflect.TypeOf(&t).Elem().String())
+	}
+
+	return r, nil
+}
+
+// MustGet is like Get, but panics when the value can not be returned.
+func MustGet[T any](m *Manager, key string) T {
+	t, err := Get[T](m, key)
+	if err != nil {
+		panic(err)
+	}
+
+	return t
+}
+
+func assignableValue(key string, v reflect.Value, t reflect.Type) (reflect.Value, error) {
+	if !v.IsValid() {
+		return reflect.Value{}, ErrorNotAssignable.Clone().
+			Set("key", key).
+			Set("from", "invalid value").
+			Set("to", t.String())
+	}
+
+	vt := v.Type()
+
+	switch {
+	case vt.AssignableTo(t):
+		return v, nil
+	case vt.Kind() == reflect.Ptr && vt.Elem().AssignableTo(t):
+		if v.IsNil() {
+			return reflect.Value{}, ErrorNotAssignable.Clone().
+				Set("key", key).
+				Set("from", "nil "+vt.String()).
+				Set("to", t.String())
+		}
+		return v.Elem(), nil
+	case t.Kind() == reflect.Ptr && vt.AssignableTo(t.Elem()) && v.CanAddr():
+		return v.Addr(), nil
+	}
+
+	return reflect.Value{}, ErrorNotAssignable.Clone().
+		Set("key", key).
+		Set("from", vt.String()).
+		Set("to", t.String())
+}
+
+func assignTo(key string, v reflect.Value, i interface{}) error {
+	to := reflect.ValueOf(i)
+	if to.Kind() != reflect.Ptr || to.IsNil() {
+		return fmt.Errorf("non-nil pointer is required: %T", i)
+	}
+
+	a, err := assignableValue(key, v, to.Type().Elem())
+	if err != nil {
+		return err
+	}
+
+	to.Elem().Set(a)
+	return nil
+}
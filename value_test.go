@@ -0,0 +1,113 @@
+package cvc
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type testValueLogConfig struct {
+	BaseGroup
+
+	Level string
+}
+
+type testValueConfig struct {
+	BaseGroup
+
+	A   int
+	Any interface{}
+	Log *testValueLogConfig
+}
+
+type testValue struct {
+	suite.Suite
+}
+
+func (t *testValue) newManager() *Manager {
+	config := &testValueConfig{
+		A:   1,
+		Log: &testValueLogConfig{Level: "debug"},
+	}
+
+	return newTestManager(t.T(), config, nil, nil)
+}
+
+func (t *testValue) TestGet() {
+	manager := t.newManager()
+
+	a, err := Get[int](manager, "a")
+	t.NoError(err)
+	t.Equal(1, a)
+
+	level, err := Get[string](manager, "log.level")
+	t.NoError(err)
+	t.Equal("debug", level)
+}
+
+func (t *testValue) TestGetGroup() {
+	manager := t.newManager()
+
+	p, err := Get[*testValueLogConfig](manager, "log")
+	t.NoError(err)
+	t.Equal("debug", p.Level)
+
+	v, err := Get[testValueLogConfig](manager, "log")
+	t.NoError(err)
+	t.Equal("debug", v.Level)
+}
+
+func (t *testValue) TestGetKeyNotFound() {
+	manager := t.newManager()
+
+	_, err := Get[int](manager, "b")
+	t.True(ErrorKeyNotFound.Equal(err))
+}
+
+func (t *testValue) TestGetNotAssignable() {
+	manager := t.newManager()
+
+	_, err := Get[string](manager, "a")
+	t.True(ErrorNotAssignable.Equal(err))
+
+	t.Panics(func() {
+		MustGet[string](manager, "a")
+	})
+	t.Equal(1, MustGet[int](manager, "a"))
+}
+
+func (t *testValue) TestGetValueNotAssignable() {
+	manager := t.newManager()
+
+	var s string
+	err := manager.GetValue("a", &s)
+	t.True(ErrorNotAssignable.Equal(err))
+
+	err = manager.GetValue("a", s)
+	t.Error(err)
+}
+
+func (t *testValue) TestGetNilInterface() {
+	manager := t.newManager()
+
+	v, err := Get[any](manager, "any")
+	t.NoError(err)
+	t.Nil(v)
+
+	_, err = Get[fmt.Stringer](manager, "any")
+	t.True(ErrorNotAssignable.Equal(err))
+
+	manager.Config().(*testValueConfig).Any = 3
+
+	v, err = Get[any](manager, "any")
+	t.NoError(err)
+	t.Equal(3, v)
+
+	_, err = Get[string](manager, "any")
+	t.True(ErrorNotAssignable.Equal(err))
+}
+
+func TestValue(t *testing.T) {
+	suite.Run(t, new(testValue))
+}